@@ -12,11 +12,15 @@ import (
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
 	"bytes"
 	"context"
+	"errors"
 	"github.com/lib/pq"
 	"github.com/yuseferi/zax/v2"
 	"io"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
@@ -33,7 +37,7 @@ func main() {
 	c := config.Get()
 	log.Info("Starting CZERTAINLY-HashiCorp-Vault-Connector", zap.String("version", version))
 	conn, _ := db.ConnectDB(c)
-	schema := config.Get().Database.Schema
+	schema := c.Database.Schema
 	err := conn.Exec("CREATE SCHEMA IF NOT EXISTS " + pq.QuoteIdentifier(schema)).Error
 	if err != nil {
 		log.Error("Error creating schema", zap.Error(err))
@@ -41,14 +45,25 @@ func main() {
 	db.MigrateDB(c)
 	discoveryRepo, _ := db.NewDiscoveryRepository(conn)
 	authorityRepo, _ := db.NewAuthorityRepository(conn)
+	issuanceRepo, _ := db.NewIssuanceRepository(conn)
 
-	DiscoveryAPIService := discovery.NewDiscoveryAPIService(discoveryRepo, authorityRepo, log)
+	discoveryRunner := discovery.NewRunner(c.Discovery.Workers, c.Discovery.RateLimit, c.Discovery.MaxDuration)
+
+	DiscoveryAPIService := discovery.NewDiscoveryAPIService(discoveryRepo, authorityRepo, discoveryRunner, c.Discovery.BatchSize, c.Discovery.ResumeGracePeriod, log)
 	DiscoveryAPIController := discovery.NewDiscoveryAPIController(DiscoveryAPIService)
+	DiscoveryAPIService.RecoverDiscoveries(c.Discovery.ResumeMode)
+	DiscoveryAPIService.CollectGarbage(c.Discovery.Retention, c.Discovery.RetentionCount, c.Discovery.GcInterval)
+	DiscoveryAPIService.RunSchedules()
+	DiscoveryAPIService.MigrateStoredCertificates()
+	DiscoveryScheduleAPIController := discovery.NewDiscoveryScheduleAPIController(DiscoveryAPIService)
 
 	AuthorityManagementAPIService := authority.NewAuthorityManagementAPIService(authorityRepo, log)
 	AuthorityManagementAPIController := authority.NewAuthorityManagementAPIController(AuthorityManagementAPIService)
 
-	CertificateManagementAPIService := authority.NewCertificateManagementAPIService(authorityRepo, log)
+	TidyAPIService := authority.NewTidyAPIService(authorityRepo, log)
+	TidyAPIController := authority.NewTidyAPIController(TidyAPIService)
+
+	CertificateManagementAPIService := authority.NewCertificateManagementAPIService(authorityRepo, issuanceRepo, c.Csr, c.Issuance, log)
 	CertificateManagementAPIController := authority.NewCertificateManagementAPIController(CertificateManagementAPIService)
 
 	DiscoveryConnectorAttributesAPIService := discovery.NewConnectorAttributesAPIService(authorityRepo, log)
@@ -64,14 +79,14 @@ func main() {
 
 	healthRouter := model.NewRouter(HealthAPIController)
 
-	authorityRouter := model.NewRouter(AuthorityConnectorAttributesAPIController, AuthorityManagementAPIController, CertificateManagementAPIController)
+	authorityRouter := model.NewRouter(AuthorityConnectorAttributesAPIController, AuthorityManagementAPIController, CertificateManagementAPIController, TidyAPIController)
 	populateRoutes(authorityRouter, "authorityProvider")
 
 	// needs to be separate as it uses v2 prefix!
 	certificateRouter := model.NewRouter(CertificateManagementAPIController)
 	populateRoutes(authorityRouter, "authorityProvider")
 
-	discoveryRouter := model.NewRouter(DiscoveryConnectorAttributesAPIController, DiscoveryAPIController)
+	discoveryRouter := model.NewRouter(DiscoveryConnectorAttributesAPIController, DiscoveryAPIController, DiscoveryScheduleAPIController)
 	populateRoutes(discoveryRouter, "discoveryProvider")
 
 	info := []model.InfoResponse{
@@ -97,11 +112,30 @@ func main() {
 	topMux.Handle("/v2/authorityProvider/", logMiddleware(certificateRouter))
 	topMux.Handle("/v1/discoveryProvider/", logMiddleware(discoveryRouter))
 
-	err = http.ListenAndServe(":"+c.Server.Port, topMux)
-	if err != nil {
-		log.Fatal(err.Error())
+	server := &http.Server{
+		Addr:    ":" + c.Server.Port,
+		Handler: topMux,
 	}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err.Error())
+		}
+	}()
 
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	log.Info("Shutting down CZERTAINLY-HashiCorp-Vault-Connector")
+	ctx, cancel := context.WithTimeout(context.Background(), c.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("Error shutting down server", zap.Error(err))
+	}
+	if err := discoveryRunner.Shutdown(ctx); err != nil {
+		log.Error("Error stopping running discoveries", zap.Error(err))
+	}
 }
 
 func logMiddleware(next http.Handler) http.Handler {
@@ -46,6 +46,17 @@ type ConnectorAttributesAPIRouter interface {
 	ValidateAttributes(http.ResponseWriter, *http.Request)
 }
 
+// TidyAPIRouter defines the required methods for binding the api requests to a responses for the TidyAPI
+// The TidyAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a TidyAPIServicer to perform the required actions, then write the service results to the http response.
+type TidyAPIRouter interface {
+	StartTidy(http.ResponseWriter, *http.Request)
+	GetTidyStatus(http.ResponseWriter, *http.Request)
+	CancelTidy(http.ResponseWriter, *http.Request)
+	GetAutoTidy(http.ResponseWriter, *http.Request)
+	UpdateAutoTidy(http.ResponseWriter, *http.Request)
+}
+
 // AuthorityManagementAPIServicer defines the api actions for the AuthorityManagementAPI service
 // This interface intended to stay up to date with the openapi yaml used to generate it,
 // while the service implementation can be ignored with the .openapi-generator-ignore file
@@ -60,7 +71,7 @@ type AuthorityManagementAPIServicer interface {
 	ListRAProfileAttributes(context.Context, string) (model.ImplResponse, error)
 	RemoveAuthorityInstance(context.Context, string) (model.ImplResponse, error)
 	UpdateAuthorityInstance(context.Context, string, model.AuthorityProviderInstanceRequestDto) (model.ImplResponse, error)
-	ValidateRAProfileAttributes(context.Context, string, []model.RequestAttributeDto) (model.ImplResponse, error)
+	ValidateRAProfileAttributes(context.Context, string, []model.Attribute) (model.ImplResponse, error)
 	RAProfileCallback(context.Context, string, string) (model.ImplResponse, error)
 }
 
@@ -75,7 +86,7 @@ type CertificateManagementAPIServicer interface {
 	ListRevokeCertificateAttributes(context.Context, string) (model.ImplResponse, error)
 	RenewCertificate(context.Context, string, model.CertificateRenewRequestDto) (model.ImplResponse, error)
 	RevokeCertificate(context.Context, string, model.CertRevocationDto) (model.ImplResponse, error)
-	ValidateIssueCertificateAttributes(context.Context, string, []model.RequestAttributeDto) (model.ImplResponse, error)
+	ValidateIssueCertificateAttributes(context.Context, string, []model.Attribute) (model.ImplResponse, error)
 	ValidateRevokeCertificateAttributes(context.Context, string, []model.RequestAttributeDto) (model.ImplResponse, error)
 }
 
@@ -88,3 +99,12 @@ type ConnectorAttributesAPIServicer interface {
 	CredentialAttributesCallback(context.Context, string) (model.ImplResponse, error)
 	ValidateAttributes(context.Context, string, []model.Attribute) (model.ImplResponse, error)
 }
+
+// TidyAPIServicer defines the api actions for the TidyAPI service
+type TidyAPIServicer interface {
+	StartTidy(context.Context, string, string, model.TidyRequestDto) (model.ImplResponse, error)
+	GetTidyStatus(context.Context, string, string) (model.ImplResponse, error)
+	CancelTidy(context.Context, string, string) (model.ImplResponse, error)
+	GetAutoTidy(context.Context, string, string) (model.ImplResponse, error)
+	UpdateAutoTidy(context.Context, string, string, model.AutoTidyRequestDto) (model.ImplResponse, error)
+}
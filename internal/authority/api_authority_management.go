@@ -343,13 +343,12 @@ func (c *AuthorityManagementAPIController) ValidateRAProfileAttributes(w http.Re
 		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
 		return
 	}
-	var requestAttributeDtoParam []model.RequestAttributeDto
 	jsonContent, err := io.ReadAll(r.Body)
 	if err != nil {
 		c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
 		return
 	}
-	model.UnmarshalAttributesValues(jsonContent)
+	requestAttributeDtoParam := model.UnmarshalAttributesValues(jsonContent)
 	for _, el := range requestAttributeDtoParam {
 		if err := model.AssertRequestAttributeDtoRequired(el); err != nil {
 			c.errorHandler(w, r, err, nil)
@@ -6,11 +6,14 @@ import (
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/vault"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	vault2 "github.com/hashicorp/vault-client-go"
 	"github.com/yuseferi/zax/v2"
 	"go.uber.org/zap"
 	"net/http"
+	"sort"
 	"strings"
 )
 
@@ -140,7 +143,7 @@ func (s *AuthorityManagementAPIService) GetCaCertificates(ctx context.Context, u
 
 	}
 	var caChainCertificates []model.CertificateDataResponseDto
-	chain, err := utils.GetCertificatesFromDer([]byte(certificateCaResponse.Data.CaChain))
+	chain, err := buildCertificateChain(ctx, nil, []string{certificateCaResponse.Data.CaChain}, true)
 	if err != nil {
 		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
 			Message: "Failed to parse certificate chain",
@@ -148,9 +151,10 @@ func (s *AuthorityManagementAPIService) GetCaCertificates(ctx context.Context, u
 
 	}
 	for _, cert := range chain {
+		serialNumber, _ := utils.ExtractSerialNumber(cert.Raw)
 		caChainCertificates = append(caChainCertificates, model.CertificateDataResponseDto{
-			CertificateData: cert,
-			Uuid:            utils.DeterministicGUID(),
+			CertificateData: base64.StdEncoding.EncodeToString(cert.Raw),
+			Uuid:            utils.DeterministicGUID(serialNumber),
 			Meta:            nil,
 			CertificateType: "X.509",
 		})
@@ -398,8 +402,83 @@ func (s *AuthorityManagementAPIService) UpdateAuthorityInstance(ctx context.Cont
 }
 
 // ValidateRAProfileAttributes - Validate RA Profile attributes
-func (s *AuthorityManagementAPIService) ValidateRAProfileAttributes(ctx context.Context, uuid string, requestAttributeDto []model.RequestAttributeDto) (model.ImplResponse, error) {
+func (s *AuthorityManagementAPIService) ValidateRAProfileAttributes(ctx context.Context, uuid string, attributes []model.Attribute) (model.ImplResponse, error) {
 	s.log.With(zax.Get(ctx)...).Info("Validating RA Profile attributes", zap.String("uuid", uuid))
+	authority, err := s.authorityRepo.FindAuthorityInstanceByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{
+			Message: "Authority not found",
+		}), nil
+	}
+
+	var messages []string
+	engineName := getRAProfileEngineName(attributes)
+	if engineName == "" {
+		messages = append(messages, "PKI secret engine must be selected")
+	}
+	role := getRAProfileRoleName(attributes)
+	if role == "" {
+		messages = append(messages, "Role must be selected")
+	}
+	if len(messages) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, messages), nil
+	}
+
+	client, err := vault.GetClient(*authority)
+	if err != nil {
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+			Message: "Failed to create vault client",
+		}), nil
+	}
+
+	//Due to the nature of its intended usage, there is no guarantee on backwards compatibility for this endpoint.
+	mounts, err := client.System.InternalUiListEnabledVisibleMounts(ctx)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error(err.Error())
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+			Message: "Failed to list secret engines: " + err.Error(),
+		}), nil
+	}
+	engineData, ok := mounts.Data.Secret[engineName+"/"].(map[string]any)
+	if !ok {
+		messages = append(messages, fmt.Sprintf("PKI secret engine '%s' does not exist or is not visible to the connector", engineName))
+		return model.Response(http.StatusUnprocessableEntity, messages), nil
+	}
+	if engineData["type"] != "pki" {
+		messages = append(messages, fmt.Sprintf("Secret engine '%s' is of type '%v', expected 'pki'", engineName, engineData["type"]))
+		return model.Response(http.StatusUnprocessableEntity, messages), nil
+	}
+
+	_, err = client.Secrets.PkiReadRole(ctx, role, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		if vault2.IsErrorStatus(err, http.StatusNotFound) {
+			messages = append(messages, fmt.Sprintf("Role '%s' does not exist in PKI secret engine '%s'", role, engineName))
+		} else {
+			messages = append(messages, fmt.Sprintf("Unable to read role '%s' from PKI secret engine '%s': %s", role, engineName, err.Error()))
+		}
+	}
+
+	missing, err := vault.MissingCapabilities(ctx, client, map[string][]string{
+		engineName + "/sign/" + role:  {"update"},
+		engineName + "/revoke":        {"update"},
+		engineName + "/cert/ca_chain": {"read"},
+	})
+	if err != nil {
+		messages = append(messages, "Unable to verify capabilities of the connector token: "+err.Error())
+	}
+	paths := make([]string, 0, len(missing))
+	for path := range missing {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		messages = append(messages, fmt.Sprintf("Connector token is missing '%s' capability on path '%s'", strings.Join(missing[path], "', '"), path))
+	}
+
+	if len(messages) > 0 {
+		s.log.With(zax.Get(ctx)...).Info("RA Profile attributes are not valid", zap.String("uuid", uuid), zap.Strings("messages", messages))
+		return model.Response(http.StatusUnprocessableEntity, messages), nil
+	}
 	return model.Response(http.StatusOK, nil), nil
 }
 
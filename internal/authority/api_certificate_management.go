@@ -284,13 +284,12 @@ func (c *CertificateManagementAPIController) ValidateIssueCertificateAttributes(
 		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
 		return
 	}
-	var requestAttributeDtoParam []model.RequestAttributeDto
-	d := json.NewDecoder(r.Body)
-	d.DisallowUnknownFields()
-	if err := d.Decode(&requestAttributeDtoParam); err != nil {
+	jsonContent, err := io.ReadAll(r.Body)
+	if err != nil {
 		c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
 		return
 	}
+	requestAttributeDtoParam := model.UnmarshalAttributesValues(jsonContent)
 	for _, el := range requestAttributeDtoParam {
 		if err := model.AssertRequestAttributeDtoRequired(el); err != nil {
 			c.errorHandler(w, r, err, nil)
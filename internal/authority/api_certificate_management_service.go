@@ -1,18 +1,23 @@
 package authority
 
 import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/config"
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/vault"
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	vault2 "github.com/hashicorp/vault-client-go"
 	"github.com/hashicorp/vault-client-go/schema"
 	"github.com/yuseferi/zax/v2"
 	"go.uber.org/zap"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // CertificateManagementAPIService is a service that implements the logic for the CertificateManagementAPIServicer
@@ -20,13 +25,19 @@ import (
 // Include any external packages or services that will be required by this service.
 type CertificateManagementAPIService struct {
 	authorityRepo *db.AuthorityRepository
+	issuanceRepo  *db.IssuanceRepository
+	csrPolicy     utils.CsrPolicy
+	issuance      config.IssuanceConfig
 	log           *zap.Logger
 }
 
 // NewCertificateManagementAPIService creates a default api service
-func NewCertificateManagementAPIService(authorityRepo *db.AuthorityRepository, logger *zap.Logger) CertificateManagementAPIServicer {
+func NewCertificateManagementAPIService(authorityRepo *db.AuthorityRepository, issuanceRepo *db.IssuanceRepository, csrPolicy utils.CsrPolicy, issuance config.IssuanceConfig, logger *zap.Logger) CertificateManagementAPIServicer {
 	return &CertificateManagementAPIService{
 		authorityRepo: authorityRepo,
+		issuanceRepo:  issuanceRepo,
+		csrPolicy:     csrPolicy,
+		issuance:      issuance,
 		log:           logger,
 	}
 }
@@ -81,36 +92,59 @@ func (s *CertificateManagementAPIService) IdentifyCertificate(ctx context.Contex
 
 // IssueCertificate - Issue Certificate
 func (s *CertificateManagementAPIService) IssueCertificate(ctx context.Context, uuid string, certificateSignRequestDto model.CertificateSignRequestDto) (model.ImplResponse, error) {
-	//TODO: refactor and merge code with renew certificate
-	raAttributes := certificateSignRequestDto.RaProfileAttributes
-	engineData := model.GetAttributeFromArrayByUUID(model.RA_PROFILE_ENGINE_ATTR, raAttributes).GetContent()[0].GetData().(map[string]interface{})
-	engineName := engineData["engineName"].(string)
-	role := model.GetAttributeFromArrayByUUID(model.RA_PROFILE_ROLE_ATTR, raAttributes).GetContent()[0].GetData().(string)
+	return s.signCertificate(ctx, uuid, certificateSignRequestDto.Pkcs10, certificateSignRequestDto.RaProfileAttributes, certificateSignRequestDto.Attributes, "Issuing certificate")
+}
+
+// signWithRole signs the Base64 encoded PKCS#10 request with the role selected in the RA profile.
+// The CSR is checked against the role policy before it is sent to Vault. The returned flag is true when Vault
+// may have signed the certificate, also when the response could not be received or processed.
+func (s *CertificateManagementAPIService) signWithRole(ctx context.Context, uuid string, pkcs10 string, raAttributes []model.Attribute, attributes []model.Attribute, operation string) (model.ImplResponse, bool, error) {
+	engineName := getRAProfileEngineName(raAttributes)
+	role := getRAProfileRoleName(raAttributes)
 	authority, err := s.authorityRepo.FindAuthorityInstanceByUUID(uuid)
 	if err != nil {
 		return model.Response(http.StatusNotFound, model.ErrorMessageDto{
 			Message: "Authority not found",
-		}), nil
+		}), false, nil
 	}
-	client, err := vault.GetClient(*authority)
+	ttl, err := getIssueTtl(attributes)
+	if err != nil {
+		return model.Response(http.StatusUnprocessableEntity, []string{err.Error()}), false, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(pkcs10)
 	if err != nil {
 		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
 			Message: err.Error(),
-		}), nil
+		}), false, nil
+
 	}
-	decoded, err := base64.StdEncoding.DecodeString(certificateSignRequestDto.Pkcs10)
+	csr, err := x509.ParseCertificateRequest(decoded)
 	if err != nil {
 		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
-			Message: err.Error(),
-		}), nil
+			Message: "failed to parse CSR: " + err.Error(),
+		}), false, nil
+	}
+	commonName := csr.Subject.CommonName
 
+	if violations := utils.CheckCsrPolicy(csr, s.csrPolicy); len(violations) > 0 {
+		s.log.With(zax.Get(ctx)...).Info("CSR violates connector policy", zap.String("common_name", commonName), zap.Strings("violations", violations))
+		return model.Response(http.StatusUnprocessableEntity, violations), false, nil
 	}
-	commonName, err := utils.ExtractCommonName(decoded)
+
+	client, err := vault.GetClient(*authority)
 	if err != nil {
 		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
 			Message: err.Error(),
-		}), nil
+		}), false, nil
+	}
 
+	roleResponse, err := client.Secrets.PkiReadRole(ctx, role, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		// the token may not be allowed to read the role, leave the policy check on Vault
+		s.log.With(zax.Get(ctx)...).Warn("Unable to read role, skipping CSR pre-check", zap.String("role", role), zap.String("engine_name", engineName), zap.Error(err))
+	} else if violations := vault.CheckCsrAgainstRole(csr, &roleResponse.Data, ttl); len(violations) > 0 {
+		s.log.With(zax.Get(ctx)...).Info("CSR violates role policy", zap.String("role", role), zap.String("engine_name", engineName), zap.Strings("violations", violations))
+		return model.Response(http.StatusUnprocessableEntity, violations), false, nil
 	}
 
 	pemBlock := &pem.Block{
@@ -123,43 +157,86 @@ func (s *CertificateManagementAPIService) IssueCertificate(ctx context.Context,
 		CommonName: commonName,
 		Csr:        string(pemBytes),
 	}
+	if ttl > 0 {
+		signRequest.Ttl = strconv.FormatInt(int64(ttl.Seconds()), 10)
+	}
 
-	s.log.With(zax.Get(ctx)...).Info("Issuing certificate", zap.String("common_name", commonName), zap.String("role", role), zap.String("engine_name", engineName))
-	certificateSignResponse, err := client.Secrets.PkiSignWithRole(ctx, role, signRequest, vault2.WithMountPath(engineName+"/"))
+	s.log.With(zax.Get(ctx)...).Info(operation, zap.String("common_name", commonName), zap.String("role", role), zap.String("engine_name", engineName))
+	// the request is not aborted when the client disconnects, Vault may sign the certificate anyway
+	signCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.issuance.SignTimeout)
+	defer cancel()
+	certificateSignResponse, err := client.Secrets.PkiSignWithRole(signCtx, role, signRequest, vault2.WithMountPath(engineName+"/"))
 	if err != nil {
 		s.log.With(zax.Get(ctx)...).Error(err.Error())
-		return model.Response(http.StatusBadRequest, model.ErrorMessageDto{
-			Message: err.Error(),
-		}), nil
-
+		var responseError *vault2.ResponseError
+		if errors.As(err, &responseError) && responseError.StatusCode >= 400 && responseError.StatusCode < 500 {
+			// Vault rejected the request, it did not sign the certificate
+			return model.Response(http.StatusBadRequest, model.ErrorMessageDto{
+				Message: err.Error(),
+			}), false, nil
+		}
+		return model.Response(http.StatusBadGateway, model.ErrorMessageDto{
+			Message: "Unable to get result of signing the certificate: " + err.Error(),
+		}), true, nil
 	}
 	certificate := certificateSignResponse.Data.Certificate
 	serialNumber := certificateSignResponse.Data.SerialNumber
 	pemBlock, _ = pem.Decode([]byte(certificate))
 	if pemBlock == nil {
 		s.log.With(zax.Get(ctx)...).Error("Failed to decode PEM file")
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+			Message: "Failed to decode PEM file",
+		}), true, nil
+	}
+	derBytes := pemBlock.Bytes
+
+	var meta []model.MetadataAttribute
+	if chainFormat := getChainFormat(attributes); chainFormat != model.CHAIN_FORMAT_NONE {
+		leaf, err := x509.ParseCertificate(derBytes)
 		if err != nil {
 			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
-				Message: "Failed to decode PEM file",
-			}), nil
-
+				Message: "failed to parse certificate: " + err.Error(),
+			}), true, nil
+		}
+		caCertificates := append([]string{certificateSignResponse.Data.IssuingCa}, certificateSignResponse.Data.CaChain...)
+		chain, err := buildCertificateChain(ctx, leaf, caCertificates, false)
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Failed to build issuer chain", zap.Error(err))
+			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+				Message: "Failed to parse certificate chain",
+			}), true, nil
+		}
+		meta, err = getChainMetadata(chain, chainFormat)
+		if err != nil {
+			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+				Message: err.Error(),
+			}), true, nil
 		}
 	}
-	derBytes := pemBlock.Bytes
 
 	CertificateDataResponseDto := model.CertificateDataResponseDto{
 		CertificateData: base64.StdEncoding.EncodeToString(derBytes),
 		Uuid:            utils.DeterministicGUID(serialNumber),
-		Meta:            nil,
+		Meta:            meta,
 		CertificateType: "X.509",
 	}
 
-	return model.Response(http.StatusOK, CertificateDataResponseDto), nil
+	return model.Response(http.StatusOK, CertificateDataResponseDto), true, nil
+}
+
+// getIssueTtl returns the TTL requested in the issue certificate attributes, or zero when not requested.
+func getIssueTtl(attributes []model.Attribute) (time.Duration, error) {
+	attribute := model.GetAttributeFromArrayByUUID(model.ISSUE_CERTIFICATE_TTL_ATTR, attributes)
+	if attribute == nil || len(attribute.GetContent()) == 0 {
+		return 0, nil
+	}
+	ttl, _ := attribute.GetContent()[0].GetData().(string)
+	return utils.ParseTtl(ttl)
 }
 
 // ListIssueCertificateAttributes - List of Attributes to issue Certificate
 func (s *CertificateManagementAPIService) ListIssueCertificateAttributes(ctx context.Context, uuid string) (model.ImplResponse, error) {
-	return model.Response(http.StatusOK, nil), nil
+	return model.Response(http.StatusOK, model.GetAttributeListBySet(model.IssueCertificateAttributes)), nil
 }
 
 // ListRevokeCertificateAttributes - List of Attributes to revoke Certificate
@@ -169,78 +246,7 @@ func (s *CertificateManagementAPIService) ListRevokeCertificateAttributes(ctx co
 
 // RenewCertificate - Renew Certificate
 func (s *CertificateManagementAPIService) RenewCertificate(ctx context.Context, uuid string, certificateRenewRequestDto model.CertificateRenewRequestDto) (model.ImplResponse, error) {
-	raAttributes := certificateRenewRequestDto.RaProfileAttributes
-	engineData := model.GetAttributeFromArrayByUUID(model.RA_PROFILE_ENGINE_ATTR, raAttributes).GetContent()[0].GetData().(map[string]interface{})
-	engineName := engineData["engineName"].(string)
-	role := model.GetAttributeFromArrayByUUID(model.RA_PROFILE_ROLE_ATTR, raAttributes).GetContent()[0].GetData().(string)
-	authority, err := s.authorityRepo.FindAuthorityInstanceByUUID(uuid)
-	if err != nil {
-		return model.Response(http.StatusNotFound, model.ErrorMessageDto{
-			Message: "Authority not found",
-		}), nil
-	}
-
-	client, err := vault.GetClient(*authority)
-	if err != nil {
-		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
-			Message: err.Error(),
-		}), nil
-	}
-	decoded, err := base64.StdEncoding.DecodeString(certificateRenewRequestDto.Pkcs10)
-	if err != nil {
-		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
-			Message: err.Error(),
-		}), nil
-
-	}
-	commonName, err := utils.ExtractCommonName(decoded)
-	if err != nil {
-		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
-			Message: err.Error(),
-		}), nil
-
-	}
-	pemBlock := &pem.Block{
-		Type:  "CERTIFICATE REQUEST", // Or "CERTIFICATE", depending on what's in the DER file
-		Bytes: decoded,
-	}
-	pemBytes := pem.EncodeToMemory(pemBlock)
-	signRequest := schema.PkiSignWithRoleRequest{
-		CommonName: commonName,
-		Csr:        string(pemBytes),
-	}
-
-	s.log.With(zax.Get(ctx)...).Info("Renewing certificate", zap.String("common_name", commonName), zap.String("role", role), zap.String("engine_name", engineName))
-	certificateSignResponse, err := client.Secrets.PkiSignWithRole(ctx, role, signRequest, vault2.WithMountPath(engineName+"/"))
-	if err != nil {
-		s.log.With(zax.Get(ctx)...).Error(err.Error())
-		return model.Response(http.StatusBadRequest, model.ErrorMessageDto{
-			Message: err.Error(),
-		}), nil
-
-	}
-	certificate := certificateSignResponse.Data.Certificate
-	serialNumber := certificateSignResponse.Data.SerialNumber
-	pemBlock, _ = pem.Decode([]byte(certificate))
-	if pemBlock == nil {
-		s.log.With(zax.Get(ctx)...).Error("Failed to decode PEM file")
-		if err != nil {
-			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
-				Message: "Failed to decode PEM file",
-			}), nil
-
-		}
-	}
-	derBytes := pemBlock.Bytes
-
-	CertificateDataResponseDto := model.CertificateDataResponseDto{
-		CertificateData: base64.StdEncoding.EncodeToString(derBytes),
-		Uuid:            utils.DeterministicGUID(serialNumber),
-		Meta:            nil,
-		CertificateType: "X.509",
-	}
-
-	return model.Response(http.StatusOK, CertificateDataResponseDto), nil
+	return s.signCertificate(ctx, uuid, certificateRenewRequestDto.Pkcs10, certificateRenewRequestDto.RaProfileAttributes, nil, "Renewing certificate")
 }
 
 // RevokeCertificate - Revoke Certificate
@@ -294,8 +300,14 @@ func (s *CertificateManagementAPIService) RevokeCertificate(ctx context.Context,
 }
 
 // ValidateIssueCertificateAttributes - Validate list of Attributes to issue Certificate
-func (s *CertificateManagementAPIService) ValidateIssueCertificateAttributes(ctx context.Context, uuid string, requestAttributeDto []model.RequestAttributeDto) (model.ImplResponse, error) {
+// The requested TTL is checked against the maximum TTL of the role when the RA profile attributes are part of the request.
+func (s *CertificateManagementAPIService) ValidateIssueCertificateAttributes(ctx context.Context, uuid string, attributes []model.Attribute) (model.ImplResponse, error) {
 	s.log.With(zax.Get(ctx)...).Info("Validating issue certificate attributes", zap.String("uuid", uuid))
+	// the issue attributes do not identify the role, the TTL is checked against the maximum TTL of the role
+	// before the certificate is signed
+	if _, err := getIssueTtl(attributes); err != nil {
+		return model.Response(http.StatusUnprocessableEntity, []string{err.Error()}), nil
+	}
 	return model.Response(http.StatusOK, nil), nil
 }
 
@@ -0,0 +1,148 @@
+package authority
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TidyAPIController binds http requests to an api service and writes the service results to the http response
+type TidyAPIController struct {
+	service      TidyAPIServicer
+	errorHandler model.ErrorHandler
+}
+
+// NewTidyAPIController creates a default api controller
+func NewTidyAPIController(s TidyAPIServicer) model.Router {
+	return &TidyAPIController{
+		service:      s,
+		errorHandler: model.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the TidyAPIController
+func (c *TidyAPIController) Routes() model.Routes {
+	return model.Routes{
+		"StartTidy": model.Route{
+			Method:      strings.ToUpper("Post"),
+			Pattern:     "/v1/authorityProvider/authorities/{uuid}/engines/{engineName}/tidy",
+			HandlerFunc: c.StartTidy,
+		},
+		"GetTidyStatus": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/authorityProvider/authorities/{uuid}/engines/{engineName}/tidy",
+			HandlerFunc: c.GetTidyStatus,
+		},
+		"CancelTidy": model.Route{
+			Method:      strings.ToUpper("Post"),
+			Pattern:     "/v1/authorityProvider/authorities/{uuid}/engines/{engineName}/tidy/cancel",
+			HandlerFunc: c.CancelTidy,
+		},
+		"GetAutoTidy": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/authorityProvider/authorities/{uuid}/engines/{engineName}/autoTidy",
+			HandlerFunc: c.GetAutoTidy,
+		},
+		"UpdateAutoTidy": model.Route{
+			Method:      strings.ToUpper("Put"),
+			Pattern:     "/v1/authorityProvider/authorities/{uuid}/engines/{engineName}/autoTidy",
+			HandlerFunc: c.UpdateAutoTidy,
+		},
+	}
+}
+
+// StartTidy - Start tidy of the PKI secret engine
+func (c *TidyAPIController) StartTidy(w http.ResponseWriter, r *http.Request) {
+	uuidParam, engineNameParam, ok := c.readEngineParams(w, r)
+	if !ok {
+		return
+	}
+	tidyRequestDtoParam := model.TidyRequestDto{}
+	if !c.readRequest(w, r, &tidyRequestDtoParam) {
+		return
+	}
+	result, err := c.service.StartTidy(r.Context(), uuidParam, engineNameParam, tidyRequestDtoParam)
+	c.writeResult(w, r, result, err)
+}
+
+// GetTidyStatus - Get status of the last tidy of the PKI secret engine
+func (c *TidyAPIController) GetTidyStatus(w http.ResponseWriter, r *http.Request) {
+	uuidParam, engineNameParam, ok := c.readEngineParams(w, r)
+	if !ok {
+		return
+	}
+	result, err := c.service.GetTidyStatus(r.Context(), uuidParam, engineNameParam)
+	c.writeResult(w, r, result, err)
+}
+
+// CancelTidy - Cancel running tidy of the PKI secret engine
+func (c *TidyAPIController) CancelTidy(w http.ResponseWriter, r *http.Request) {
+	uuidParam, engineNameParam, ok := c.readEngineParams(w, r)
+	if !ok {
+		return
+	}
+	result, err := c.service.CancelTidy(r.Context(), uuidParam, engineNameParam)
+	c.writeResult(w, r, result, err)
+}
+
+// GetAutoTidy - Get automatic tidy configuration of the PKI secret engine
+func (c *TidyAPIController) GetAutoTidy(w http.ResponseWriter, r *http.Request) {
+	uuidParam, engineNameParam, ok := c.readEngineParams(w, r)
+	if !ok {
+		return
+	}
+	result, err := c.service.GetAutoTidy(r.Context(), uuidParam, engineNameParam)
+	c.writeResult(w, r, result, err)
+}
+
+// UpdateAutoTidy - Update automatic tidy configuration of the PKI secret engine
+func (c *TidyAPIController) UpdateAutoTidy(w http.ResponseWriter, r *http.Request) {
+	uuidParam, engineNameParam, ok := c.readEngineParams(w, r)
+	if !ok {
+		return
+	}
+	autoTidyRequestDtoParam := model.AutoTidyRequestDto{}
+	if !c.readRequest(w, r, &autoTidyRequestDtoParam) {
+		return
+	}
+	result, err := c.service.UpdateAutoTidy(r.Context(), uuidParam, engineNameParam, autoTidyRequestDtoParam)
+	c.writeResult(w, r, result, err)
+}
+
+func (c *TidyAPIController) readEngineParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	params := mux.Vars(r)
+	uuidParam := params["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return "", "", false
+	}
+	engineNameParam := params["engineName"]
+	if engineNameParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "engineName"}, nil)
+		return "", "", false
+	}
+	return uuidParam, engineNameParam, true
+}
+
+func (c *TidyAPIController) readRequest(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(request); err != nil {
+		c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
+		return false
+	}
+	return true
+}
+
+func (c *TidyAPIController) writeResult(w http.ResponseWriter, r *http.Request, result model.ImplResponse, err error) {
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = model.EncodeJSONResponse(result.Body, &result.Code, w)
+}
@@ -0,0 +1,225 @@
+package authority
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/vault"
+	"context"
+	"errors"
+	"net/http"
+
+	vault2 "github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// States of the tidy operation reported by the PKI engine
+const (
+	TIDY_STATE_RUNNING    = "Running"
+	TIDY_STATE_CANCELLING = "Cancelling"
+)
+
+// TidyAPIService is a service that implements the logic for the TidyAPIServicer
+type TidyAPIService struct {
+	authorityRepo *db.AuthorityRepository
+	log           *zap.Logger
+}
+
+// NewTidyAPIService creates a default api service
+func NewTidyAPIService(authorityRepo *db.AuthorityRepository, logger *zap.Logger) TidyAPIServicer {
+	return &TidyAPIService{
+		authorityRepo: authorityRepo,
+		log:           logger,
+	}
+}
+
+// StartTidy - Start tidy of the PKI secret engine
+func (s *TidyAPIService) StartTidy(ctx context.Context, uuid string, engineName string, request model.TidyRequestDto) (model.ImplResponse, error) {
+	if !request.TidyCertStore && !request.TidyRevokedCerts && !request.TidyExpiredIssuers {
+		return model.Response(http.StatusUnprocessableEntity, []string{"At least one of tidyCertStore, tidyRevokedCerts or tidyExpiredIssuers must be enabled"}), nil
+	}
+	client, response := s.getClient(uuid)
+	if client == nil {
+		return response, nil
+	}
+	status, err := client.Secrets.PkiTidyStatus(ctx, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "read tidy status"), nil
+	}
+	if isTidyRunning(status.Data) {
+		return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Tidy of PKI secret engine " + engineName + " is already running."}), nil
+	}
+
+	_, err = client.Secrets.PkiTidy(ctx, schema.PkiTidyRequest{
+		TidyCertStore:      request.TidyCertStore,
+		TidyRevokedCerts:   request.TidyRevokedCerts,
+		TidyExpiredIssuers: request.TidyExpiredIssuers,
+		SafetyBuffer:       request.SafetyBuffer,
+		IssuerSafetyBuffer: request.IssuerSafetyBuffer,
+		PauseDuration:      request.PauseDuration,
+	}, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "start tidy"), nil
+	}
+	s.log.With(zax.Get(ctx)...).Info("Tidy of PKI secret engine started", zap.String("authority_uuid", uuid), zap.String("engine", engineName),
+		zap.Bool("tidy_cert_store", request.TidyCertStore), zap.Bool("tidy_revoked_certs", request.TidyRevokedCerts), zap.Bool("tidy_expired_issuers", request.TidyExpiredIssuers))
+
+	status, err = client.Secrets.PkiTidyStatus(ctx, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "read tidy status"), nil
+	}
+	return model.Response(http.StatusAccepted, toTidyStatusDto(status.Data)), nil
+}
+
+// GetTidyStatus - Get status of the last tidy of the PKI secret engine
+func (s *TidyAPIService) GetTidyStatus(ctx context.Context, uuid string, engineName string) (model.ImplResponse, error) {
+	client, response := s.getClient(uuid)
+	if client == nil {
+		return response, nil
+	}
+	status, err := client.Secrets.PkiTidyStatus(ctx, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "read tidy status"), nil
+	}
+	return model.Response(http.StatusOK, toTidyStatusDto(status.Data)), nil
+}
+
+// CancelTidy - Cancel running tidy of the PKI secret engine
+func (s *TidyAPIService) CancelTidy(ctx context.Context, uuid string, engineName string) (model.ImplResponse, error) {
+	client, response := s.getClient(uuid)
+	if client == nil {
+		return response, nil
+	}
+	status, err := client.Secrets.PkiTidyStatus(ctx, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "read tidy status"), nil
+	}
+	if status.Data.State != TIDY_STATE_RUNNING {
+		return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Tidy of PKI secret engine " + engineName + " is not running."}), nil
+	}
+	cancelled, err := client.Secrets.PkiTidyCancel(ctx, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "cancel tidy"), nil
+	}
+	s.log.With(zax.Get(ctx)...).Info("Tidy of PKI secret engine cancelled", zap.String("authority_uuid", uuid), zap.String("engine", engineName))
+	return model.Response(http.StatusOK, toTidyStatusDto(schema.PkiTidyStatusResponse(cancelled.Data))), nil
+}
+
+// GetAutoTidy - Get automatic tidy configuration of the PKI secret engine
+func (s *TidyAPIService) GetAutoTidy(ctx context.Context, uuid string, engineName string) (model.ImplResponse, error) {
+	client, response := s.getClient(uuid)
+	if client == nil {
+		return response, nil
+	}
+	config, err := client.Secrets.PkiReadAutoTidyConfiguration(ctx, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "read automatic tidy configuration"), nil
+	}
+	return model.Response(http.StatusOK, toAutoTidyDto(config.Data)), nil
+}
+
+// UpdateAutoTidy - Update automatic tidy configuration of the PKI secret engine
+func (s *TidyAPIService) UpdateAutoTidy(ctx context.Context, uuid string, engineName string, request model.AutoTidyRequestDto) (model.ImplResponse, error) {
+	client, response := s.getClient(uuid)
+	if client == nil {
+		return response, nil
+	}
+	// the options are written as a map, the generated request omits the options which are disabled
+	options := make(map[string]interface{})
+	setOption(options, "enabled", request.Enabled)
+	setOption(options, "interval_duration", request.IntervalDuration)
+	setOption(options, "tidy_cert_store", request.TidyCertStore)
+	setOption(options, "tidy_revoked_certs", request.TidyRevokedCerts)
+	setOption(options, "tidy_expired_issuers", request.TidyExpiredIssuers)
+	setOption(options, "safety_buffer", request.SafetyBuffer)
+	setOption(options, "issuer_safety_buffer", request.IssuerSafetyBuffer)
+	setOption(options, "pause_duration", request.PauseDuration)
+	if _, err := client.Write(ctx, engineName+"/config/auto-tidy", options); err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "update automatic tidy configuration"), nil
+	}
+	s.log.With(zax.Get(ctx)...).Info("Automatic tidy of PKI secret engine updated", zap.String("authority_uuid", uuid), zap.String("engine", engineName), zap.Any("options", options))
+
+	config, err := client.Secrets.PkiReadAutoTidyConfiguration(ctx, vault2.WithMountPath(engineName+"/"))
+	if err != nil {
+		return s.tidyErrorResponse(ctx, err, engineName, "read automatic tidy configuration"), nil
+	}
+	return model.Response(http.StatusOK, toAutoTidyDto(config.Data)), nil
+}
+
+// getClient returns the Vault client of the authority, or the response when the client can not be created.
+func (s *TidyAPIService) getClient(uuid string) (*vault2.Client, model.ImplResponse) {
+	authority, err := s.authorityRepo.FindAuthorityInstanceByUUID(uuid)
+	if err != nil {
+		return nil, model.Response(http.StatusNotFound, model.ErrorMessageDto{
+			Message: "Authority not found",
+		})
+	}
+	client, err := vault.GetClient(*authority)
+	if err != nil {
+		return nil, model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+			Message: "Failed to create vault client",
+		})
+	}
+	return client, model.ImplResponse{}
+}
+
+// tidyErrorResponse returns the response to the failed request to the PKI secret engine. Invalid options are reported
+// as validation errors, the engine which does not exist or is not PKI as not found.
+func (s *TidyAPIService) tidyErrorResponse(ctx context.Context, err error, engineName string, action string) model.ImplResponse {
+	var responseError *vault2.ResponseError
+	if errors.As(err, &responseError) {
+		switch responseError.StatusCode {
+		case http.StatusBadRequest:
+			return model.Response(http.StatusUnprocessableEntity, responseError.Errors)
+		case http.StatusNotFound, http.StatusMethodNotAllowed:
+			return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "PKI secret engine " + engineName + " not found."})
+		}
+	}
+	s.log.With(zax.Get(ctx)...).Error("Failed to "+action, zap.String("engine", engineName), zap.Error(err))
+	return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Failed to " + action + " of PKI secret engine " + engineName})
+}
+
+func setOption[T any](options map[string]interface{}, name string, value *T) {
+	if value != nil {
+		options[name] = *value
+	}
+}
+
+func isTidyRunning(status schema.PkiTidyStatusResponse) bool {
+	return status.State == TIDY_STATE_RUNNING || status.State == TIDY_STATE_CANCELLING
+}
+
+func toTidyStatusDto(status schema.PkiTidyStatusResponse) model.TidyStatusDto {
+	return model.TidyStatusDto{
+		State:                   status.State,
+		Message:                 status.Message,
+		Error:                   status.Error,
+		TimeStarted:             status.TimeStarted,
+		TimeFinished:            status.TimeFinished,
+		LastAutoTidyFinished:    status.LastAutoTidyFinished,
+		TidyCertStore:           status.TidyCertStore,
+		TidyRevokedCerts:        status.TidyRevokedCerts,
+		TidyExpiredIssuers:      status.TidyExpiredIssuers,
+		SafetyBuffer:            status.SafetyBuffer,
+		IssuerSafetyBuffer:      status.IssuerSafetyBuffer,
+		CertStoreDeletedCount:   status.CertStoreDeletedCount,
+		RevokedCertDeletedCount: status.RevokedCertDeletedCount,
+		MissingIssuerCertCount:  status.MissingIssuerCertCount,
+		CurrentCertStoreCount:   status.CurrentCertStoreCount,
+		CurrentRevokedCertCount: status.CurrentRevokedCertCount,
+	}
+}
+
+func toAutoTidyDto(config schema.PkiReadAutoTidyConfigurationResponse) model.AutoTidyDto {
+	return model.AutoTidyDto{
+		Enabled:            config.Enabled,
+		IntervalDuration:   config.IntervalDuration,
+		TidyCertStore:      config.TidyCertStore,
+		TidyRevokedCerts:   config.TidyRevokedCerts,
+		TidyExpiredIssuers: config.TidyExpiredIssuers,
+		SafetyBuffer:       config.SafetyBuffer,
+		IssuerSafetyBuffer: config.IssuerSafetyBuffer,
+		PauseDuration:      config.PauseDuration,
+	}
+}
@@ -0,0 +1,92 @@
+package authority
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	vault2 "github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"go.uber.org/zap"
+)
+
+func TestIsTidyRunning(t *testing.T) {
+	tests := []struct {
+		state   string
+		running bool
+	}{
+		{state: TIDY_STATE_RUNNING, running: true},
+		{state: TIDY_STATE_CANCELLING, running: true},
+		{state: "Inactive"},
+		{state: "Finished"},
+		{state: "Error"},
+		{state: "Cancelled"},
+	}
+	for _, test := range tests {
+		if running := isTidyRunning(schema.PkiTidyStatusResponse{State: test.state}); running != test.running {
+			t.Fatalf("expected running %v for state %q, got %v", test.running, test.state, running)
+		}
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	enabled := false
+	interval := "12h"
+	var unset *string
+	options := make(map[string]interface{})
+	setOption(options, "enabled", &enabled)
+	setOption(options, "interval_duration", &interval)
+	setOption(options, "safety_buffer", unset)
+
+	if len(options) != 2 {
+		t.Fatalf("expected only the set options, got %v", options)
+	}
+	if value, ok := options["enabled"]; !ok || value != false {
+		t.Fatalf("expected disabled option to be written, got %v", options)
+	}
+	if options["interval_duration"] != "12h" {
+		t.Fatalf("unexpected interval duration %v", options["interval_duration"])
+	}
+	if _, ok := options["safety_buffer"]; ok {
+		t.Fatalf("expected unset option to be omitted, got %v", options)
+	}
+}
+
+func TestTidyErrorResponse(t *testing.T) {
+	s := &TidyAPIService{log: zap.NewNop()}
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "bad request", err: &vault2.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"invalid safety_buffer"}}, code: http.StatusUnprocessableEntity},
+		{name: "not found", err: &vault2.ResponseError{StatusCode: http.StatusNotFound}, code: http.StatusNotFound},
+		{name: "method not allowed", err: &vault2.ResponseError{StatusCode: http.StatusMethodNotAllowed}, code: http.StatusNotFound},
+		{name: "wrapped not found", err: fmt.Errorf("request failed: %w", &vault2.ResponseError{StatusCode: http.StatusNotFound}), code: http.StatusNotFound},
+		{name: "forbidden", err: &vault2.ResponseError{StatusCode: http.StatusForbidden}, code: http.StatusInternalServerError},
+		{name: "connection error", err: errors.New("connection refused"), code: http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := s.tidyErrorResponse(context.Background(), test.err, "pki", "start tidy")
+			if response.Code != test.code {
+				t.Fatalf("expected code %d, got %d", test.code, response.Code)
+			}
+			switch body := response.Body.(type) {
+			case []string:
+				if test.code != http.StatusUnprocessableEntity || len(body) != 1 || body[0] != "invalid safety_buffer" {
+					t.Fatalf("unexpected validation errors %v", body)
+				}
+			case model.ErrorMessageDto:
+				if test.code == http.StatusUnprocessableEntity || body.Message == "" {
+					t.Fatalf("unexpected error message %q", body.Message)
+				}
+			default:
+				t.Fatalf("unexpected body %v", response.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package authority
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+)
+
+// buildCertificateChain orders the PEM encoded certificates returned by Vault into the chain of the leaf certificate,
+// from the leaf to the root. When completeWithAia is set, the chain is completed using AIA when the root is missing,
+// otherwise only the certificates returned by Vault are used, so the issuance does not depend on the AIA servers.
+// When leaf is nil, the chain starts with the certificate that did not issue any other certificate.
+func buildCertificateChain(ctx context.Context, leaf *x509.Certificate, pemCertificates []string, completeWithAia bool) ([]*x509.Certificate, error) {
+	certificates, err := utils.ParseCertificatesPem(pemCertificates)
+	if err != nil {
+		return nil, err
+	}
+	chain := utils.OrderCertificateChain(leaf, certificates)
+	if !completeWithAia {
+		return chain, nil
+	}
+	return utils.CompleteCertificateChain(ctx, chain), nil
+}
+
+// getChainFormat returns the issuer chain format requested in the issue certificate attributes.
+func getChainFormat(attributes []model.Attribute) string {
+	attribute := model.GetAttributeFromArrayByUUID(model.ISSUE_CERTIFICATE_CHAIN_FORMAT_ATTR, attributes)
+	if attribute == nil || len(attribute.GetContent()) == 0 {
+		return model.CHAIN_FORMAT_METADATA
+	}
+	format, _ := attribute.GetContent()[0].GetData().(string)
+	switch format {
+	case model.CHAIN_FORMAT_NONE, model.CHAIN_FORMAT_PKCS7:
+		return format
+	}
+	return model.CHAIN_FORMAT_METADATA
+}
+
+// getChainMetadata returns the issuer chain of the certificate as metadata in the requested format.
+// The chain starts with the issued certificate, which is part of the PKCS#7 bundle only.
+func getChainMetadata(chain []*x509.Certificate, format string) ([]model.MetadataAttribute, error) {
+	switch format {
+	case model.CHAIN_FORMAT_METADATA:
+		if len(chain) < 2 {
+			return nil, nil
+		}
+		var content []model.AttributeContent
+		for _, certificate := range chain[1:] {
+			content = append(content, model.StringAttributeContent{
+				Reference: certificate.Subject.String(),
+				Data:      base64.StdEncoding.EncodeToString(certificate.Raw),
+			})
+		}
+		return []model.MetadataAttribute{
+			{
+				Uuid:        model.CERTIFICATE_CA_CHAIN_META_ATTR,
+				Name:        "caChain",
+				Description: "Base64 encoded CA certificates of the issuer chain ordered from the issuer to the root",
+				Content:     content,
+				Type:        model.META,
+				ContentType: model.STRING,
+				Properties: model.MetadataAttributeProperties{
+					Label:   "CA chain",
+					Visible: true,
+				},
+			},
+		}, nil
+	case model.CHAIN_FORMAT_PKCS7:
+		bundle, err := utils.EncodePkcs7Bundle(chain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode PKCS#7 bundle: %v", err)
+		}
+		return []model.MetadataAttribute{
+			{
+				Uuid:        model.CERTIFICATE_PKCS7_META_ATTR,
+				Name:        "pkcs7",
+				Description: "Base64 encoded PKCS#7 bundle with the certificate and its issuer chain",
+				Content: []model.AttributeContent{
+					model.StringAttributeContent{
+						Data: base64.StdEncoding.EncodeToString(bundle),
+					},
+				},
+				Type:        model.META,
+				ContentType: model.STRING,
+				Properties: model.MetadataAttributeProperties{
+					Label:   "PKCS#7 bundle",
+					Visible: true,
+				},
+			},
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,112 @@
+package authority
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// issuancePollInterval is the interval of checking the state of the identical request which is in progress
+const issuancePollInterval = 500 * time.Millisecond
+
+// signCertificate signs the request only once within the retention window of the issuance ledger.
+// A retried identical request gets the response of the first one, and identical requests processed
+// at the same time wait for the first one to finish.
+func (s *CertificateManagementAPIService) signCertificate(ctx context.Context, uuid string, pkcs10 string, raAttributes []model.Attribute, attributes []model.Attribute, operation string) (model.ImplResponse, error) {
+	if s.issuanceRepo == nil {
+		response, _, err := s.signWithRole(ctx, uuid, pkcs10, raAttributes, attributes, operation)
+		return response, err
+	}
+	cfg := s.issuance
+	fingerprint := issuanceFingerprint(uuid, getRAProfileEngineName(raAttributes), getRAProfileRoleName(raAttributes), pkcs10, operation, attributes)
+	log := s.log.With(zax.Get(ctx)...).With(zap.String("fingerprint", fingerprint))
+
+	deadline := time.Now().Add(cfg.WaitTimeout)
+	for {
+		claimed, record, err := s.issuanceRepo.ClaimIssuance(fingerprint, uuid, time.Now().Add(cfg.Retention))
+		if err != nil {
+			log.Warn("Unable to use issuance ledger, signing without it", zap.Error(err))
+			response, _, err := s.signWithRole(ctx, uuid, pkcs10, raAttributes, attributes, operation)
+			return response, err
+		}
+		if claimed {
+			break
+		}
+		if record.Status == db.ISSUANCE_COMPLETED {
+			log.Info("Returning certificate of identical request already issued", zap.String("serial_number", record.SerialNumber))
+			return model.Response(http.StatusOK, json.RawMessage(record.Response)), nil
+		}
+		taken, err := s.issuanceRepo.TakeOverIssuance(record, time.Now().Add(-cfg.LockTimeout))
+		if err != nil {
+			log.Warn("Unable to take over stale issuance", zap.Error(err))
+		}
+		if taken {
+			log.Info("Taking over stale issuance of identical request")
+			break
+		}
+		if time.Now().After(deadline) {
+			return model.Response(http.StatusConflict, model.ErrorMessageDto{
+				Message: "Identical request is already being processed",
+			}), nil
+		}
+		select {
+		case <-ctx.Done():
+			return model.Response(http.StatusServiceUnavailable, model.ErrorMessageDto{
+				Message: ctx.Err().Error(),
+			}), nil
+		case <-time.After(issuancePollInterval):
+		}
+	}
+
+	response, signed, err := s.signWithRole(ctx, uuid, pkcs10, raAttributes, attributes, operation)
+	certificateData, ok := response.Body.(model.CertificateDataResponseDto)
+	if err != nil || response.Code != http.StatusOK || !ok {
+		if signed {
+			// the certificate may have been issued, the record is kept until the lock timeout,
+			// so that the retried request does not sign it again right away
+			log.Warn("Result of signing unknown, keeping issuance locked", zap.Int("status", response.Code))
+			return response, err
+		}
+		if err := s.issuanceRepo.ReleaseIssuance(fingerprint); err != nil {
+			log.Error("Unable to release issuance", zap.Error(err))
+		}
+		return response, err
+	}
+
+	body, err := json.Marshal(certificateData)
+	if err == nil {
+		var serialNumber string
+		if decoded, decodeErr := base64.StdEncoding.DecodeString(certificateData.CertificateData); decodeErr == nil {
+			serialNumber, _ = utils.ExtractSerialNumber(decoded)
+		}
+		err = s.issuanceRepo.CompleteIssuance(fingerprint, serialNumber, body)
+	}
+	if err != nil {
+		log.Error("Unable to store issued certificate in issuance ledger", zap.Error(err))
+	}
+	if err := s.issuanceRepo.DeleteExpiredIssuances(); err != nil {
+		log.Warn("Unable to delete expired issuance records", zap.Error(err))
+	}
+	return response, nil
+}
+
+// issuanceFingerprint identifies the sign request by the authority, engine, role, CSR and requested attributes.
+func issuanceFingerprint(uuid string, engineName string, role string, pkcs10 string, operation string, attributes []model.Attribute) string {
+	marshaledAttributes, _ := json.Marshal(attributes)
+	hash := sha256.New()
+	for _, part := range []string{uuid, engineName, role, pkcs10, operation, string(marshaledAttributes)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
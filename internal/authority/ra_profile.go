@@ -0,0 +1,31 @@
+package authority
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+)
+
+// getRAProfileEngineName returns the name of the PKI secret engine selected in the RA profile attributes,
+// or empty string when the engine is not selected.
+func getRAProfileEngineName(attributes []model.Attribute) string {
+	attribute := model.GetAttributeFromArrayByUUID(model.RA_PROFILE_ENGINE_ATTR, attributes)
+	if attribute == nil || len(attribute.GetContent()) == 0 {
+		return ""
+	}
+	engineData, ok := attribute.GetContent()[0].GetData().(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	engineName, _ := engineData["engineName"].(string)
+	return engineName
+}
+
+// getRAProfileRoleName returns the name of the Vault role selected in the RA profile attributes,
+// or empty string when the role is not selected.
+func getRAProfileRoleName(attributes []model.Attribute) string {
+	attribute := model.GetAttributeFromArrayByUUID(model.RA_PROFILE_ROLE_ATTR, attributes)
+	if attribute == nil || len(attribute.GetContent()) == 0 {
+		return ""
+	}
+	role, _ := attribute.GetContent()[0].GetData().(string)
+	return role
+}
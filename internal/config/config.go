@@ -2,12 +2,27 @@ package config
 
 import (
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/logger"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
 )
 
+// IssuanceConfig defines how the issuance ledger deduplicates identical sign requests
+type IssuanceConfig struct {
+	Retention   time.Duration
+	LockTimeout time.Duration
+	WaitTimeout time.Duration
+	SignTimeout time.Duration
+}
+
 type Config struct {
 	Server struct {
-		Port string
+		Port            string
+		ShutdownTimeout time.Duration
 	}
 	Database struct {
 		Name     string
@@ -19,8 +34,27 @@ type Config struct {
 		Schema   string
 		SslMode  string
 	}
+	Csr       utils.CsrPolicy
+	Issuance  IssuanceConfig
+	Discovery struct {
+		Workers           int
+		RateLimit         float64
+		BatchSize         int
+		MaxDuration       time.Duration
+		ResumeMode        string
+		ResumeGracePeriod time.Duration
+		Retention         time.Duration
+		RetentionCount    int
+		GcInterval        time.Duration
+	}
 }
 
+// Modes of handling the discoveries interrupted by restart of the connector
+const (
+	DISCOVERY_RESUME = "resume"
+	DISCOVERY_FAIL   = "fail"
+)
+
 var config Config
 
 func Get() Config {
@@ -69,5 +103,109 @@ func Get() Config {
 		config.Database.SslMode = "require"
 	}
 
+	config.Csr.VerifySignature = getBool("CSR_VERIFY_SIGNATURE", true)
+	config.Csr.MinRsaKeySize = getInt("CSR_MIN_RSA_KEY_SIZE", 2048)
+	config.Csr.AllowedEcCurves = getList("CSR_ALLOWED_EC_CURVES", "P-256,P-384,P-521")
+	config.Csr.AllowEd25519 = getBool("CSR_ALLOW_ED25519", true)
+	config.Csr.ForbiddenSignatureHashes = getList("CSR_FORBIDDEN_SIGNATURE_HASHES", "SHA1,MD5")
+	config.Csr.FipsOnly = getBool("CSR_FIPS_ONLY", false)
+
+	config.Issuance.Retention = getDuration("ISSUANCE_RETENTION", 24*time.Hour)
+	config.Issuance.LockTimeout = getDuration("ISSUANCE_LOCK_TIMEOUT", 5*time.Minute)
+	config.Issuance.WaitTimeout = getDuration("ISSUANCE_WAIT_TIMEOUT", 30*time.Second)
+	config.Issuance.SignTimeout = getDuration("ISSUANCE_SIGN_TIMEOUT", time.Minute)
+	if config.Issuance.SignTimeout == 0 {
+		config.Issuance.SignTimeout = time.Minute
+	}
+	if config.Issuance.SignTimeout >= config.Issuance.LockTimeout {
+		// the issuance could be taken over while Vault is still signing it
+		l.Warn("Sign timeout should be shorter than the lock timeout", zap.Duration("sign_timeout", config.Issuance.SignTimeout), zap.Duration("lock_timeout", config.Issuance.LockTimeout))
+	}
+
+	config.Server.ShutdownTimeout = getDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second)
+
+	config.Discovery.Workers = max(getInt("DISCOVERY_WORKERS", 4), 1)
+	config.Discovery.RateLimit = getFloat("DISCOVERY_RATE_LIMIT", 50)
+	config.Discovery.BatchSize = max(getInt("DISCOVERY_BATCH_SIZE", 500), 1)
+	config.Discovery.MaxDuration = getDuration("DISCOVERY_MAX_DURATION", 24*time.Hour)
+	config.Discovery.ResumeMode = strings.ToLower(os.Getenv("DISCOVERY_RESUME_MODE"))
+	if config.Discovery.ResumeMode != DISCOVERY_RESUME && config.Discovery.ResumeMode != DISCOVERY_FAIL {
+		if config.Discovery.ResumeMode != "" {
+			l.Warn("Invalid discovery resume mode, using default", zap.String("value", config.Discovery.ResumeMode), zap.String("default", DISCOVERY_RESUME))
+		}
+		config.Discovery.ResumeMode = DISCOVERY_RESUME
+	}
+	config.Discovery.ResumeGracePeriod = getDuration("DISCOVERY_RESUME_GRACE_PERIOD", time.Minute)
+	config.Discovery.Retention = getDuration("DISCOVERY_RETENTION", 0)
+	config.Discovery.RetentionCount = max(getInt("DISCOVERY_RETENTION_COUNT", 0), 0)
+	config.Discovery.GcInterval = getDuration("DISCOVERY_GC_INTERVAL", time.Hour)
+
 	return config
 }
+
+func getBool(name string, defaultValue bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Get().Warn("Invalid boolean value, using default", zap.String("variable", name), zap.String("value", value), zap.Bool("default", defaultValue))
+		return defaultValue
+	}
+	return result
+}
+
+func getInt(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Get().Warn("Invalid integer value, using default", zap.String("variable", name), zap.String("value", value), zap.Int("default", defaultValue))
+		return defaultValue
+	}
+	return result
+}
+
+func getFloat(name string, defaultValue float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logger.Get().Warn("Invalid number value, using default", zap.String("variable", name), zap.String("value", value), zap.Float64("default", defaultValue))
+		return defaultValue
+	}
+	return result
+}
+
+func getDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil || result < 0 {
+		logger.Get().Warn("Invalid duration value, using default", zap.String("variable", name), zap.String("value", value), zap.Duration("default", defaultValue))
+		return defaultValue
+	}
+	return result
+}
+
+func getList(name string, defaultValue string) []string {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		value = defaultValue
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -23,6 +23,7 @@ func ConnectDB(config config.Config) (db *gorm.DB, err error) {
 		DSN:                  dsn,
 		PreferSimpleProtocol: true,
 	}), &gorm.Config{
+		TranslateError: true,
 		NamingStrategy: schema.NamingStrategy{
 			TablePrefix:   config.Database.Schema + ".",
 			SingularTable: false,
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"time"
+)
+
+// Changes of the certificates between two discoveries
+const (
+	DIFF_ADDED   = "added"
+	DIFF_REMOVED = "removed"
+	DIFF_CHANGED = "changed"
+)
+
+// DiffCertificate is the certificate added, removed or with the revocation changed between two discoveries.
+// The revocation is not set for the discovery which did not find the certificate.
+type DiffCertificate struct {
+	Change        string
+	Id            uint
+	UUID          string
+	SerialNumber  string
+	Subject       string
+	NotAfter      *time.Time
+	Engine        string
+	RevokedBefore *bool
+	Revoked       *bool
+}
+
+// diffQuery selects the certificates found only by the discovery @to, only by the discovery @from,
+// and by both of them with different revocation
+const diffQuery = `
+	SELECT 'added' AS change, c.id, c.uuid, c.serial_number, c.subject, c.not_after, c.engine, NULL::boolean AS revoked_before, t.revoked
+	FROM discovery_certificates t JOIN certificates c ON c.id = t.certificate_id
+	WHERE t.discovery_id = @to
+		AND NOT EXISTS (SELECT 1 FROM discovery_certificates f WHERE f.discovery_id = @from AND f.certificate_id = t.certificate_id)
+	UNION ALL
+	SELECT 'removed', c.id, c.uuid, c.serial_number, c.subject, c.not_after, c.engine, f.revoked, NULL::boolean
+	FROM discovery_certificates f JOIN certificates c ON c.id = f.certificate_id
+	WHERE f.discovery_id = @from
+		AND NOT EXISTS (SELECT 1 FROM discovery_certificates t WHERE t.discovery_id = @to AND t.certificate_id = f.certificate_id)
+	UNION ALL
+	SELECT 'changed', c.id, c.uuid, c.serial_number, c.subject, c.not_after, c.engine, f.revoked, t.revoked
+	FROM discovery_certificates f
+		JOIN discovery_certificates t ON t.certificate_id = f.certificate_id AND t.discovery_id = @to
+		JOIN certificates c ON c.id = f.certificate_id
+	WHERE f.discovery_id = @from AND f.revoked <> t.revoked`
+
+// CountDiff returns the number of added, removed and changed certificates between the discoveries.
+func (d *DiscoveryRepository) CountDiff(from *Discovery, to *Discovery) (map[string]int64, error) {
+	var rows []struct {
+		Change string
+		Count  int64
+	}
+	err := d.db.Raw("SELECT change, count(*) AS count FROM ("+diffQuery+") diff GROUP BY change",
+		map[string]interface{}{"from": from.Id, "to": to.Id}).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{DIFF_ADDED: 0, DIFF_REMOVED: 0, DIFF_CHANGED: 0}
+	for _, row := range rows {
+		counts[row.Change] = row.Count
+	}
+	return counts, nil
+}
+
+// ListDiff returns the page of the certificates which changed between the discoveries, ordered by the change.
+// Only the given changes are listed, or all of them when none is given. Zero limit returns all certificates.
+func (d *DiscoveryRepository) ListDiff(from *Discovery, to *Discovery, changes []string, offset int, limit int) ([]DiffCertificate, error) {
+	query := "SELECT * FROM (" + diffQuery + ") diff"
+	params := map[string]interface{}{"from": from.Id, "to": to.Id}
+	if len(changes) > 0 {
+		query += " WHERE change IN @changes"
+		params["changes"] = changes
+	}
+	query += " ORDER BY change, id"
+	if limit > 0 {
+		query += " LIMIT @limit OFFSET @offset"
+		params["limit"] = limit
+		params["offset"] = offset
+	}
+	var certificates []DiffCertificate
+	if err := d.db.Raw(query, params).Scan(&certificates).Error; err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// FindPreviousDiscovery returns the latest completed discovery of the same authority created before the discovery.
+// The discoveries which did not complete found only part of the certificates, so they are not compared.
+func (d *DiscoveryRepository) FindPreviousDiscovery(discovery *Discovery) (*Discovery, error) {
+	var previous Discovery
+	err := d.db.Where("authority_uuid = ? AND status IN ? AND id <> ? AND (created_at < ? OR (created_at = ? AND id < ?))",
+		discovery.AuthorityUuid, []string{"COMPLETED", "WARNING"}, discovery.Id, discovery.CreatedAt, discovery.CreatedAt, discovery.Id).
+		Order("created_at DESC, id DESC").First(&previous).Error
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
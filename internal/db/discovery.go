@@ -1,30 +1,113 @@
 package db
 
 import (
+	"errors"
+	"fmt"
 	"math"
+	"time"
 
+	"github.com/lib/pq"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Discovery struct {
-	Id           uint `gorm:"primarykey"`
-	UUID         string
-	Name         string
-	Status       string
-	Meta         datatypes.JSON
-	Certificates []Certificate `gorm:"many2many:discovery_certificates;"`
+	Id                    uint `gorm:"primarykey"`
+	UUID                  string
+	Name                  string
+	Status                string
+	Meta                  datatypes.JSON
+	EnginesTotal          int
+	EnginesDone           int
+	CertificatesListed    int
+	CertificatesRead      int
+	CertificatesFailed    int
+	StartedAt             *time.Time
+	EstimatedCompletionAt *time.Time
+	AuthorityUuid         string
+	HeartbeatAt           *time.Time
+	CancelRequestedAt     *time.Time
+	CreatedAt             time.Time
+	ScheduleId            *uint
+	Engines               []DiscoveryEngine
+	Certificates          []Certificate `gorm:"many2many:discovery_certificates;"`
+}
+
+const (
+	ENGINE_KIND_PKI = "pki"
+	ENGINE_KIND_KV  = "kv"
+)
+
+// ErrCertificateExists is the error of the certificate which can not get the uuid of another stored certificate
+var ErrCertificateExists = errors.New("certificate with the uuid is stored already")
+
+// DiscoveryEngine is the secrets engine searched by the discovery, done when all its certificates are stored
+type DiscoveryEngine struct {
+	Id          uint `gorm:"primarykey"`
+	DiscoveryId uint
+	Engine      string
+	Kind        string
+	Done        bool
 }
 
 type Certificate struct {
 	Id            uint `gorm:"primarykey"`
 	SerialNumber  string
 	UUID          string
-	Base64Content string
-	Meta          datatypes.JSON
-	Discoveries   []Discovery `gorm:"many2many:discovery_certificates;"`
+	AuthorityUuid string
+	Engine        string
+	IssuerId      string
+	Fingerprint   string
+	NotAfter      *time.Time
+	Subject       string
+	// parsed from the certificate, so that the certificates can be searched
+	CommonName         string
+	Sans               pq.StringArray `gorm:"type:varchar[]"`
+	IssuerDn           string
+	NotBefore          *time.Time
+	KeyAlgorithm       string
+	KeySize            int
+	SignatureAlgorithm string
+	Base64Content      string
+	Meta               datatypes.JSON
+	// SortValue is the value of the sort column of the listed certificate used for the cursor of the next page
+	SortValue string `gorm:"->"`
+	// Revoked is the revocation of the certificate when discovered, stored with its association to the discovery
+	Revoked     bool        `gorm:"->"`
+	Discoveries []Discovery `gorm:"many2many:discovery_certificates;"`
+}
+
+// DiscoveryFailure is the engine or certificate which could not be discovered
+type DiscoveryFailure struct {
+	Id           uint `gorm:"primarykey"`
+	DiscoveryId  uint
+	Engine       string
+	SerialNumber string
+	Reason       string
+	CreatedAt    time.Time
+}
+
+// DiscoveryCertificate is the association of the certificate to the discovery
+type DiscoveryCertificate struct {
+	CertificateId uint `gorm:"primaryKey"`
+	DiscoveryId   uint `gorm:"primaryKey"`
+	Revoked       bool
 }
 
+// EngineSerial is the certificate of the PKI engine already read by the incremental discovery
+type EngineSerial struct {
+	AuthorityUuid string `gorm:"primaryKey"`
+	Engine        string `gorm:"primaryKey"`
+	SerialNumber  string `gorm:"primaryKey"`
+	CertificateId uint
+	Revoked       bool
+	CreatedAt     time.Time
+}
+
+// engineSerialsChunk limits the number of serial numbers used as parameters of single statement
+const engineSerialsChunk = 1000
+
 type DiscoveryRepository struct {
 	db *gorm.DB
 }
@@ -41,34 +124,57 @@ func (d *DiscoveryRepository) CreateDiscovery(discovery *Discovery) error {
 	return nil
 }
 
-func (d *DiscoveryRepository) AssociateCertificatesToDiscovery(discovery *Discovery, certificates ...*Certificate) error {
-	for _, certificate := range certificates {
-		d.db.FirstOrCreate(&certificate, Certificate{SerialNumber: certificate.SerialNumber})
-	}
-	assoc := d.db.Model(&discovery).Association("Certificates")
-	err := assoc.Append(&certificates)
-	if err != nil {
-		return err
+// AddCertificatesToDiscovery stores the batch of certificates and associates them to the discovery.
+// Certificates which are already stored are reused.
+func (d *DiscoveryRepository) AddCertificatesToDiscovery(discovery *Discovery, certificates []*Certificate) error {
+	if len(certificates) == 0 {
+		return nil
 	}
-	return nil
-}
-
-func (d *DiscoveryRepository) CreateDiscoveryAndAssociateCertificates(discovery *Discovery, certificates ...*Certificate) error {
-	d.db.Create(&discovery)
-	for _, certificate := range certificates {
-		d.db.FirstOrCreate(&certificate, Certificate{SerialNumber: certificate.SerialNumber})
-		assoc := d.db.Model(&discovery).Association("Certificates")
-		err := assoc.Append(&certificate)
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		// the same certificate can not be updated twice by single statement
+		unique := make(map[string]*Certificate, len(certificates))
+		uuids := make([]string, 0, len(certificates))
+		for _, certificate := range certificates {
+			if _, ok := unique[certificate.UUID]; !ok {
+				uuids = append(uuids, certificate.UUID)
+			}
+			unique[certificate.UUID] = certificate
+		}
+		rows := make([]*Certificate, 0, len(uuids))
+		for _, uuid := range uuids {
+			rows = append(rows, unique[uuid])
+		}
+		// already stored certificates get the content and metadata of the latest discovery
+		err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "uuid"}},
+			DoUpdates: clause.AssignmentColumns([]string{"base64_content", "meta", "fingerprint", "not_after", "subject", "common_name", "sans", "issuer_dn", "not_before", "key_algorithm", "key_size", "signature_algorithm"}),
+		}).Create(&rows).Error
 		if err != nil {
 			return err
 		}
-	}
-	return nil
+
+		var stored []Certificate
+		if err := tx.Select("id", "uuid").Where("uuid IN ?", uuids).Find(&stored).Error; err != nil {
+			return err
+		}
+		ids := make(map[string]uint, len(stored))
+		for _, certificate := range stored {
+			ids[certificate.UUID] = certificate.Id
+		}
+		for _, certificate := range certificates {
+			certificate.Id = ids[certificate.UUID]
+		}
+		associations := make([]DiscoveryCertificate, 0, len(rows))
+		for _, certificate := range rows {
+			associations = append(associations, DiscoveryCertificate{CertificateId: certificate.Id, DiscoveryId: discovery.Id, Revoked: certificate.Revoked})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&associations).Error
+	})
 }
 
 func (d *DiscoveryRepository) FindDiscoveryByUUID(uuid string) (*Discovery, error) {
 	var discovery Discovery
-	if err := d.db.Preload("Certificates").First(&discovery, "uuid = ?", uuid).Error; err != nil {
+	if err := d.db.First(&discovery, "uuid = ?", uuid).Error; err != nil {
 		return nil, err
 	}
 	return &discovery, nil
@@ -82,39 +188,386 @@ func (d *DiscoveryRepository) UpdateDiscovery(discovery *Discovery) error {
 	return nil
 }
 
-func (d *DiscoveryRepository) List(pagination Pagination, discovery *Discovery) (*Pagination, error) {
+// UpdateDiscoveryStatus updates only the status of the discovery, so that the progress stored in the meantime is kept.
+func (d *DiscoveryRepository) UpdateDiscoveryStatus(discovery *Discovery, status string) error {
+	discovery.Status = status
+	return d.db.Model(discovery).Update("status", status).Error
+}
+
+// UpdateDiscoveryProgress stores the progress counters of the running discovery.
+func (d *DiscoveryRepository) UpdateDiscoveryProgress(discovery *Discovery) error {
+	return d.db.Model(discovery).Select("engines_total", "engines_done", "certificates_listed", "certificates_read", "certificates_failed", "started_at", "estimated_completion_at", "heartbeat_at").Updates(discovery).Error
+}
+
+// ListDiscoveryEngines returns the engines of the discovery in the order they are searched.
+func (d *DiscoveryRepository) ListDiscoveryEngines(discovery *Discovery) ([]DiscoveryEngine, error) {
+	var engines []DiscoveryEngine
+	if err := d.db.Where("discovery_id = ?", discovery.Id).Order("id").Find(&engines).Error; err != nil {
+		return nil, err
+	}
+	return engines, nil
+}
+
+// CompleteDiscoveryEngine records that all certificates of the engine are stored.
+func (d *DiscoveryRepository) CompleteDiscoveryEngine(engine *DiscoveryEngine) error {
+	engine.Done = true
+	return d.db.Model(engine).Update("done", true).Error
+}
+
+// ListStaleDiscoveries returns the discoveries in progress which did not store progress since the time.
+func (d *DiscoveryRepository) ListStaleDiscoveries(before time.Time) ([]Discovery, error) {
+	var discoveries []Discovery
+	err := d.db.Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", "IN_PROGRESS", before).Order("id").Find(&discoveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return discoveries, nil
+}
+
+// ClaimStaleDiscovery takes over the stale discovery. It returns false when the discovery was taken over by someone else
+// or is not stale anymore.
+func (d *DiscoveryRepository) ClaimStaleDiscovery(discovery *Discovery, before time.Time) (bool, error) {
+	now := time.Now()
+	result := d.db.Model(&Discovery{}).
+		Where("id = ? AND status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", discovery.Id, "IN_PROGRESS", before).
+		Update("heartbeat_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	discovery.HeartbeatAt = &now
+	return result.RowsAffected == 1, nil
+}
+
+// RequestDiscoveryCancel records the request to cancel the discovery in progress, which is running on another instance.
+func (d *DiscoveryRepository) RequestDiscoveryCancel(discovery *Discovery) error {
+	now := time.Now()
+	discovery.CancelRequestedAt = &now
+	return d.db.Model(discovery).Where("status = ?", "IN_PROGRESS").Update("cancel_requested_at", now).Error
+}
+
+// IsDiscoveryCancelRequested returns true when the cancel of the discovery was requested on another instance.
+func (d *DiscoveryRepository) IsDiscoveryCancelRequested(discovery *Discovery) (bool, error) {
+	var count int64
+	err := d.db.Model(&Discovery{}).Where("id = ? AND cancel_requested_at IS NOT NULL", discovery.Id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ReleaseDiscovery clears the heartbeat of the discovery in progress, so that it is taken over without waiting.
+func (d *DiscoveryRepository) ReleaseDiscovery(discovery *Discovery) error {
+	discovery.HeartbeatAt = nil
+	return d.db.Model(discovery).Update("heartbeat_at", nil).Error
+}
+
+func (d *DiscoveryRepository) AddDiscoveryFailure(failure *DiscoveryFailure) error {
+	return d.db.Create(failure).Error
+}
+
+// ListDiscoveryFailures returns up to limit failures of the discovery and the total number of them.
+func (d *DiscoveryRepository) ListDiscoveryFailures(discovery *Discovery, limit int) ([]DiscoveryFailure, int64, error) {
+	var failures []DiscoveryFailure
+	var count int64
+	if err := d.db.Model(&DiscoveryFailure{}).Where("discovery_id = ?", discovery.Id).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	err := d.db.Where("discovery_id = ?", discovery.Id).Order("id").Limit(limit).Find(&failures).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return failures, count, nil
+}
+
+// ListEngineSerials returns the certificates of the engine already read by the incremental discovery.
+func (d *DiscoveryRepository) ListEngineSerials(authorityUuid string, engine string) ([]EngineSerial, error) {
+	var serials []EngineSerial
+	err := d.db.Where("authority_uuid = ? AND engine = ?", authorityUuid, engine).Find(&serials).Error
+	if err != nil {
+		return nil, err
+	}
+	return serials, nil
+}
+
+// AddEngineSerials remembers the certificates read by the incremental discovery.
+func (d *DiscoveryRepository) AddEngineSerials(serials []EngineSerial) error {
+	if len(serials) == 0 {
+		return nil
+	}
+	return d.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "authority_uuid"}, {Name: "engine"}, {Name: "serial_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"certificate_id", "revoked"}),
+	}).CreateInBatches(&serials, engineSerialsChunk).Error
+}
+
+// MarkEngineSerialsRevoked marks the certificates of the engine as revoked, including the revocation in the metadata
+// of the stored certificates.
+func (d *DiscoveryRepository) MarkEngineSerialsRevoked(authorityUuid string, engine string, serialNumbers []string) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(serialNumbers); start += engineSerialsChunk {
+			chunk := serialNumbers[start:min(start+engineSerialsChunk, len(serialNumbers))]
+			var ids []uint
+			err := tx.Model(&EngineSerial{}).
+				Where("authority_uuid = ? AND engine = ? AND serial_number IN ?", authorityUuid, engine, chunk).
+				Pluck("certificate_id", &ids).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&EngineSerial{}).
+				Where("authority_uuid = ? AND engine = ? AND serial_number IN ?", authorityUuid, engine, chunk).
+				Update("revoked", true).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			err = tx.Model(&Certificate{}).Where("id IN ?", ids).
+				Update("meta", gorm.Expr(`(coalesce(meta, '{}')::jsonb || '{"revoked": true}')::text`)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindCertificatesByIds returns the stored certificates with given IDs.
+func (d *DiscoveryRepository) FindCertificatesByIds(ids []uint) ([]*Certificate, error) {
 	var certificates []*Certificate
-	page := pagination.Page
-	pageSize := pagination.Limit
-	var count int
-	offset := (page - 1) * pageSize
-	d.db.Table("certificates").Select("certificates.*").
+	if len(ids) == 0 {
+		return certificates, nil
+	}
+	if err := d.db.Where("id IN ?", ids).Find(&certificates).Error; err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// AssociateCertificateIdsToDiscovery associates already stored certificates to the discovery with their stored revocation
+// and returns the IDs of the associated certificates. Certificates removed in the meantime by the garbage collection
+// are not returned, the returned certificates are locked until they are associated, so that they are not removed.
+func (d *DiscoveryRepository) AssociateCertificateIdsToDiscovery(discovery *Discovery, ids []uint) ([]uint, error) {
+	associated := make([]uint, 0, len(ids))
+	for start := 0; start < len(ids); start += engineSerialsChunk {
+		chunk := ids[start:min(start+engineSerialsChunk, len(ids))]
+		var chunkIds []uint
+		err := d.db.Raw(`WITH existing AS (SELECT id, meta FROM certificates WHERE id IN ? FOR SHARE),
+			inserted AS (INSERT INTO discovery_certificates (certificate_id, discovery_id, revoked)
+				SELECT id, ?, CASE WHEN meta IS NULL OR meta = '' THEN false ELSE coalesce((meta::jsonb ->> 'revoked')::boolean, false) END
+				FROM existing ON CONFLICT DO NOTHING)
+			SELECT id FROM existing`, chunk, discovery.Id).Scan(&chunkIds).Error
+		if err != nil {
+			return nil, err
+		}
+		associated = append(associated, chunkIds...)
+	}
+	return associated, nil
+}
+
+// ListDiscoveryRuns returns up to limit latest runs of the discovery with the name and the total number of them.
+func (d *DiscoveryRepository) ListDiscoveryRuns(name string, limit int) ([]Discovery, int64, error) {
+	var discoveries []Discovery
+	var count int64
+	if err := d.db.Model(&Discovery{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	err := d.db.Where("name = ?", name).Order("created_at DESC, id DESC").Limit(limit).Find(&discoveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return discoveries, count, nil
+}
+
+// CountDiscoveryCertificates returns the number of certificates associated to each of the discoveries.
+func (d *DiscoveryRepository) CountDiscoveryCertificates(ids []uint) (map[uint]int64, error) {
+	var rows []struct {
+		DiscoveryId uint
+		Count       int64
+	}
+	counts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	err := d.db.Model(&DiscoveryCertificate{}).Select("discovery_id, count(*) AS count").
+		Where("discovery_id IN ?", ids).Group("discovery_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.DiscoveryId] = row.Count
+	}
+	return counts, nil
+}
+
+func (d *DiscoveryRepository) CountDiscoveryFailures(discovery *Discovery) (int64, error) {
+	var count int64
+	err := d.db.Model(&DiscoveryFailure{}).Where("discovery_id = ?", discovery.Id).Count(&count).Error
+	return count, err
+}
+
+// sortColumn is the column the certificates of the discovery can be sorted by
+type sortColumn struct {
+	expression string
+	cast       string
+}
+
+// sortColumns are the columns the certificates can be sorted by, missing values are sorted first
+var sortColumns = map[string]sortColumn{
+	SORT_SERIAL_NUMBER: {expression: "certificates.serial_number", cast: "varchar"},
+	SORT_NOT_AFTER:     {expression: "coalesce(certificates.not_after, '-infinity'::timestamp)", cast: "timestamp"},
+	SORT_SUBJECT:       {expression: "coalesce(certificates.subject, '')", cast: "varchar"},
+	SORT_NOT_BEFORE:    {expression: "coalesce(certificates.not_before, '-infinity'::timestamp)", cast: "timestamp"},
+	SORT_COMMON_NAME:   {expression: "coalesce(certificates.common_name, '')", cast: "varchar"},
+}
+
+const (
+	SORT_SERIAL_NUMBER = "serialNumber"
+	SORT_NOT_AFTER     = "notAfter"
+	SORT_SUBJECT       = "subject"
+	SORT_NOT_BEFORE    = "notBefore"
+	SORT_COMMON_NAME   = "commonName"
+)
+
+// SORTS are the fields the certificates can be sorted by
+var SORTS = []string{SORT_SERIAL_NUMBER, SORT_NOT_AFTER, SORT_SUBJECT, SORT_NOT_BEFORE, SORT_COMMON_NAME}
+
+// IsSortSupported returns true when the certificates can be sorted by the field.
+func IsSortSupported(sort string) bool {
+	_, ok := sortColumns[sort]
+	return ok
+}
+
+// List returns the page of the certificates of the discovery ordered by the sort column and ID. When the cursor
+// is set, the page follows the certificate of the cursor instead of the page number.
+func (d *DiscoveryRepository) List(pagination Pagination, discovery *Discovery) (*Pagination, error) {
+	query := d.db.Table("certificates").
 		Joins("JOIN discovery_certificates ON discovery_certificates.certificate_id = certificates.id").
-		Where("discovery_certificates.discovery_id = ?", discovery.Id).
-		Offset(offset).Limit(pageSize).Find(&certificates)
+		Where("discovery_certificates.discovery_id = ?", discovery.Id)
+	return paginate(query, pagination)
+}
+
+// paginate returns the page of the certificates selected by the query, see List.
+func paginate(query *gorm.DB, pagination Pagination) (*Pagination, error) {
+	var certificates []*Certificate
+	column, ok := sortColumns[pagination.GetSort()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort %s", pagination.Sort)
+	}
+
+	var count int64
+	if err := query.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		return nil, err
+	}
+
+	direction := "ASC"
+	comparison := ">"
+	if pagination.Desc {
+		direction = "DESC"
+		comparison = "<"
+	}
+	query = query.Select("certificates.*, (" + column.expression + ")::varchar AS sort_value").
+		Order(column.expression + " " + direction).Order("certificates.id " + direction).
+		Limit(pagination.GetLimit())
+	if pagination.Cursor != nil {
+		query = query.Where("("+column.expression+", certificates.id) "+comparison+" (?::"+column.cast+", ?)", pagination.Cursor.Value, pagination.Cursor.Id)
+	} else {
+		query = query.Offset(pagination.GetOffset())
+	}
+	if err := query.Find(&certificates).Error; err != nil {
+		return nil, err
+	}
 
 	pagination.Rows = certificates
-	pagination.TotalRows = int64(len(discovery.Certificates))
-	totalPages := int(math.Ceil(float64(count) / float64(pagination.Limit)))
-	pagination.TotalPages = totalPages
+	pagination.TotalRows = count
+	pagination.TotalPages = int(math.Ceil(float64(count) / float64(pagination.GetLimit())))
+	if len(certificates) == pagination.GetLimit() {
+		last := certificates[len(certificates)-1]
+		pagination.Next = &Cursor{Value: last.SortValue, Id: last.Id}
+	}
 	return &pagination, nil
 }
 
-func (d *DiscoveryRepository) DeleteDiscovery(discovery *Discovery) error {
-	result := d.db.Delete(&discovery)
-	if result.Error != nil {
-		return result.Error
+// StreamDiscoveryCertificates calls the function for each certificate of the discovery, with the revocation when discovered.
+// The certificates are read from the database one by one, so that all of them are never held in memory.
+func (d *DiscoveryRepository) StreamDiscoveryCertificates(discovery *Discovery, f func(certificate *Certificate) error) error {
+	rows, err := d.db.Model(&Certificate{}).
+		Select("certificates.*, discovery_certificates.revoked").
+		Joins("JOIN discovery_certificates ON discovery_certificates.certificate_id = certificates.id").
+		Where("discovery_certificates.discovery_id = ?", discovery.Id).
+		Order("certificates.id").
+		Rows()
+	if err != nil {
+		return err
 	}
-	return nil
+	defer rows.Close()
+	for rows.Next() {
+		var certificate Certificate
+		if err := d.db.ScanRows(rows, &certificate); err != nil {
+			return err
+		}
+		if err := f(&certificate); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DeleteDiscovery deletes the discovery together with the certificates not associated to any other discovery.
+func (d *DiscoveryRepository) DeleteDiscovery(discovery *Discovery) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM certificates WHERE id IN (SELECT certificate_id FROM discovery_certificates WHERE discovery_id = ?)
+			AND NOT EXISTS (SELECT 1 FROM discovery_certificates dc WHERE dc.certificate_id = certificates.id AND dc.discovery_id <> ?)`, discovery.Id, discovery.Id).Error
+		if err != nil {
+			return err
+		}
+		// associations, failures and engines of the discovery are deleted by cascade
+		return tx.Delete(discovery).Error
+	})
+}
+
+// DeleteExpiredDiscoveries deletes the finished discoveries created before the time and returns the number of them.
+func (d *DiscoveryRepository) DeleteExpiredDiscoveries(before time.Time) (int64, error) {
+	result := d.db.Where("status <> ? AND created_at < ?", "IN_PROGRESS", before).Delete(&Discovery{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExcessDiscoveries keeps the latest finished discoveries of each authority and deletes the others.
+// It returns the number of deleted discoveries.
+func (d *DiscoveryRepository) DeleteExcessDiscoveries(keep int) (int64, error) {
+	result := d.db.Exec(`DELETE FROM discoveries WHERE id IN (
+		SELECT id FROM (
+			SELECT id, row_number() OVER (PARTITION BY authority_uuid ORDER BY created_at DESC, id DESC) AS position
+			FROM discoveries WHERE status <> ?
+		) ranked WHERE position > ?)`, "IN_PROGRESS", keep)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteOrphanedCertificates deletes up to limit certificates not associated to any discovery and returns the number of them.
+// Certificates locked by the running discovery are skipped, they are being associated to it.
+func (d *DiscoveryRepository) DeleteOrphanedCertificates(limit int) (int64, error) {
+	result := d.db.Exec(`DELETE FROM certificates WHERE id IN (
+		SELECT id FROM certificates c WHERE NOT EXISTS (SELECT 1 FROM discovery_certificates dc WHERE dc.certificate_id = c.id)
+		LIMIT ? FOR UPDATE SKIP LOCKED)`, limit)
+	return result.RowsAffected, result.Error
 }
 
 type Pagination struct {
 	Limit      int         `json:"limit,omitempty"`
 	Page       int         `json:"page,omitempty"`
 	Sort       string      `json:"sort,omitempty"`
+	Desc       bool        `json:"desc,omitempty"`
+	Cursor     *Cursor     `json:"cursor,omitempty"`
 	TotalRows  int64       `json:"total_rows"`
 	TotalPages int         `json:"total_pages"`
 	Rows       interface{} `json:"rows"`
+	Next       *Cursor     `json:"next,omitempty"`
+}
+
+// Cursor is the position of the row in the sorted rows for the keyset pagination
+type Cursor struct {
+	Value string `json:"v"`
+	Id    uint   `json:"id"`
 }
 
 func (p *Pagination) GetOffset() int {
@@ -134,3 +587,54 @@ func (p *Pagination) GetPage() int {
 	}
 	return p.Page
 }
+
+func (p *Pagination) GetSort() string {
+	if p.Sort == "" {
+		p.Sort = SORT_SERIAL_NUMBER
+	}
+	return p.Sort
+}
+
+// ListCertificatesWithoutFingerprint returns up to limit certificates with the id greater than after,
+// which were stored before their fingerprint was added, ordered by the id.
+func (d *DiscoveryRepository) ListCertificatesWithoutFingerprint(after uint, limit int) ([]*Certificate, error) {
+	var certificates []*Certificate
+	err := d.db.Where("fingerprint IS NULL AND id > ?", after).Order("id").Limit(limit).Find(&certificates).Error
+	if err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// UpdateCertificateIdentity sets the uuid and the fingerprint of the stored certificate. When the certificate with
+// the uuid is stored already, because it was discovered again before the identity was updated, the discoveries
+// of the certificate are moved to it and the certificate is deleted.
+// ErrCertificateExists is returned when the certificate with the uuid was stored concurrently by the discovery.
+func (d *DiscoveryRepository) UpdateCertificateIdentity(certificate *Certificate, uuid string, fingerprint string) error {
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if uuid != certificate.UUID {
+			var existing Certificate
+			err := tx.Select("id").Where("uuid = ?", uuid).Take(&existing).Error
+			if err == nil {
+				err = tx.Exec(`
+					UPDATE discovery_certificates SET certificate_id = ?
+					WHERE certificate_id = ?
+						AND discovery_id NOT IN (SELECT discovery_id FROM discovery_certificates WHERE certificate_id = ?)`,
+					existing.Id, certificate.Id, existing.Id).Error
+				if err != nil {
+					return err
+				}
+				// the remaining associations are deleted by cascade
+				return tx.Delete(&Certificate{}, certificate.Id).Error
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		return tx.Model(certificate).Updates(map[string]interface{}{"uuid": uuid, "fingerprint": fingerprint}).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrCertificateExists
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ISSUANCE_IN_PROGRESS = "IN_PROGRESS"
+	ISSUANCE_COMPLETED   = "COMPLETED"
+)
+
+// IssuanceRecord is an entry of the issuance ledger identifying the sign request by its fingerprint
+type IssuanceRecord struct {
+	Id            uint `gorm:"primarykey"`
+	Fingerprint   string
+	AuthorityUuid string
+	Status        string
+	SerialNumber  string
+	Response      datatypes.JSON
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type IssuanceRepository struct {
+	db *gorm.DB
+}
+
+func NewIssuanceRepository(db *gorm.DB) (*IssuanceRepository, error) {
+	return &IssuanceRepository{db: db}, nil
+}
+
+// ClaimIssuance creates the in progress record for the fingerprint. When the record already exists and is not expired,
+// it is returned and claimed is false.
+func (d *IssuanceRepository) ClaimIssuance(fingerprint string, authorityUuid string, expiresAt time.Time) (bool, *IssuanceRecord, error) {
+	err := d.db.Where("fingerprint = ? AND expires_at < ?", fingerprint, time.Now()).Delete(&IssuanceRecord{}).Error
+	if err != nil {
+		return false, nil, err
+	}
+	record := IssuanceRecord{
+		Fingerprint:   fingerprint,
+		AuthorityUuid: authorityUuid,
+		Status:        ISSUANCE_IN_PROGRESS,
+		ExpiresAt:     expiresAt,
+	}
+	result := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return false, nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, &record, nil
+	}
+	existing, err := d.FindIssuanceByFingerprint(fingerprint)
+	if err != nil {
+		return false, nil, err
+	}
+	return false, existing, nil
+}
+
+// TakeOverIssuance claims the in progress record which was not updated since staleBefore,
+// e.g. because the connector instance processing the request was stopped.
+func (d *IssuanceRepository) TakeOverIssuance(record *IssuanceRecord, staleBefore time.Time) (bool, error) {
+	result := d.db.Model(&IssuanceRecord{}).
+		Where("id = ? AND status = ? AND updated_at < ?", record.Id, ISSUANCE_IN_PROGRESS, staleBefore).
+		Update("updated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CompleteIssuance stores the response of the issuance so that the identical request gets the same certificate.
+func (d *IssuanceRepository) CompleteIssuance(fingerprint string, serialNumber string, response []byte) error {
+	return d.db.Model(&IssuanceRecord{}).Where("fingerprint = ?", fingerprint).Updates(map[string]interface{}{
+		"status":        ISSUANCE_COMPLETED,
+		"serial_number": serialNumber,
+		"response":      datatypes.JSON(response),
+		"updated_at":    time.Now(),
+	}).Error
+}
+
+// ReleaseIssuance removes the record of the failed issuance, so that the request can be retried.
+func (d *IssuanceRepository) ReleaseIssuance(fingerprint string) error {
+	return d.db.Where("fingerprint = ? AND status = ?", fingerprint, ISSUANCE_IN_PROGRESS).Delete(&IssuanceRecord{}).Error
+}
+
+func (d *IssuanceRepository) FindIssuanceByFingerprint(fingerprint string) (*IssuanceRecord, error) {
+	var record IssuanceRecord
+	if err := d.db.First(&record, "fingerprint = ?", fingerprint).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteExpiredIssuances removes the records out of the retention window.
+func (d *IssuanceRepository) DeleteExpiredIssuances() error {
+	return d.db.Where("expires_at < ?", time.Now()).Delete(&IssuanceRecord{}).Error
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"time"
+)
+
+// DiscoverySchedule is the discovery started repeatedly by the connector according to the cron expression
+type DiscoverySchedule struct {
+	Id                   uint `gorm:"primarykey"`
+	UUID                 string
+	Name                 string
+	Cron                 string
+	Kind                 string
+	Attributes           string
+	Enabled              bool
+	NextRunAt            *time.Time
+	LastRunAt            *time.Time
+	LastSuccessAt        *time.Time
+	LastSuccessDiscovery string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func (d *DiscoveryRepository) CreateSchedule(schedule *DiscoverySchedule) error {
+	return d.db.Create(schedule).Error
+}
+
+func (d *DiscoveryRepository) FindScheduleByUUID(uuid string) (*DiscoverySchedule, error) {
+	var schedule DiscoverySchedule
+	if err := d.db.First(&schedule, "uuid = ?", uuid).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (d *DiscoveryRepository) FindScheduleByName(name string) (*DiscoverySchedule, error) {
+	var schedule DiscoverySchedule
+	if err := d.db.First(&schedule, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (d *DiscoveryRepository) ListSchedules() ([]DiscoverySchedule, error) {
+	var schedules []DiscoverySchedule
+	if err := d.db.Order("name").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// UpdateScheduleDefinition stores the definition of the schedule, so that the runs recorded in the meantime are kept.
+func (d *DiscoveryRepository) UpdateScheduleDefinition(schedule *DiscoverySchedule) error {
+	return d.db.Model(schedule).Select("name", "cron", "kind", "attributes", "enabled", "next_run_at", "updated_at").Updates(schedule).Error
+}
+
+// DeleteSchedule deletes the schedule, the discoveries started by it are kept.
+func (d *DiscoveryRepository) DeleteSchedule(schedule *DiscoverySchedule) error {
+	return d.db.Delete(schedule).Error
+}
+
+// ListDueSchedules returns the enabled schedules which should have been run before the time.
+func (d *DiscoveryRepository) ListDueSchedules(before time.Time) ([]DiscoverySchedule, error) {
+	var schedules []DiscoverySchedule
+	err := d.db.Where("enabled AND next_run_at <= ?", before).Order("next_run_at").Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// ClaimScheduleRun moves the due schedule to its next run. It returns false when the run was claimed by someone else
+// or the schedule was changed in the meantime.
+func (d *DiscoveryRepository) ClaimScheduleRun(schedule *DiscoverySchedule, nextRunAt *time.Time) (bool, error) {
+	now := time.Now()
+	result := d.db.Model(&DiscoverySchedule{}).
+		Where("id = ? AND enabled AND next_run_at = ?", schedule.Id, schedule.NextRunAt).
+		Updates(map[string]interface{}{"next_run_at": nextRunAt, "last_run_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	schedule.NextRunAt = nextRunAt
+	schedule.LastRunAt = &now
+	return result.RowsAffected == 1, nil
+}
+
+// RecordScheduleSuccess records the discovery started by the schedule which completed successfully, with or without warnings.
+func (d *DiscoveryRepository) RecordScheduleSuccess(scheduleId uint, discovery *Discovery) error {
+	return d.db.Model(&DiscoverySchedule{}).Where("id = ?", scheduleId).
+		Updates(map[string]interface{}{"last_success_at": time.Now(), "last_success_discovery": discovery.UUID}).Error
+}
+
+// ListScheduleRuns returns up to limit latest discoveries started by the schedule and the total number of them.
+func (d *DiscoveryRepository) ListScheduleRuns(schedule *DiscoverySchedule, limit int) ([]Discovery, int64, error) {
+	var discoveries []Discovery
+	var count int64
+	if err := d.db.Model(&Discovery{}).Where("schedule_id = ?", schedule.Id).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	err := d.db.Where("schedule_id = ?", schedule.Id).Order("created_at DESC, id DESC").Limit(limit).Find(&discoveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return discoveries, count, nil
+}
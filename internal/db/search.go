@@ -0,0 +1,99 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CertificateSearch are the conditions the searched certificates must all match, empty conditions are ignored.
+// The common name and issuer can contain * matching any characters, the text conditions ignore case.
+type CertificateSearch struct {
+	AuthorityUuid      string
+	DiscoveryId        uint
+	Engine             string
+	CommonName         string
+	San                string
+	IssuerDn           string
+	KeyAlgorithm       string
+	KeySize            int
+	SignatureAlgorithm string
+	Fingerprint        string
+	NotAfterFrom       *time.Time
+	NotAfterTo         *time.Time
+}
+
+// SearchCertificates returns the page of the stored certificates matching the search, see List.
+func (d *DiscoveryRepository) SearchCertificates(search CertificateSearch, pagination Pagination) (*Pagination, error) {
+	query := d.db.Table("certificates")
+	if search.DiscoveryId != 0 {
+		query = query.Joins("JOIN discovery_certificates ON discovery_certificates.certificate_id = certificates.id").
+			Where("discovery_certificates.discovery_id = ?", search.DiscoveryId)
+	}
+	if search.AuthorityUuid != "" {
+		query = query.Where("certificates.authority_uuid = ?", search.AuthorityUuid)
+	}
+	if search.Engine != "" {
+		query = query.Where("certificates.engine = ?", search.Engine)
+	}
+	if search.CommonName != "" {
+		query = query.Where("lower(certificates.common_name) LIKE ?", toLikePattern(strings.ToLower(search.CommonName)))
+	}
+	if search.San != "" {
+		query = query.Where("certificates.sans @> ARRAY[?]::varchar[]", strings.ToLower(search.San))
+	}
+	if search.IssuerDn != "" {
+		query = query.Where("lower(certificates.issuer_dn) LIKE ?", toLikePattern(strings.ToLower(search.IssuerDn)))
+	}
+	if search.KeyAlgorithm != "" {
+		query = query.Where("lower(certificates.key_algorithm) = ?", strings.ToLower(search.KeyAlgorithm))
+	}
+	if search.KeySize != 0 {
+		query = query.Where("certificates.key_size = ?", search.KeySize)
+	}
+	if search.SignatureAlgorithm != "" {
+		query = query.Where("lower(certificates.signature_algorithm) = ?", strings.ToLower(search.SignatureAlgorithm))
+	}
+	if search.Fingerprint != "" {
+		query = query.Where("certificates.fingerprint = ?", strings.ToLower(search.Fingerprint))
+	}
+	if search.NotAfterFrom != nil {
+		query = query.Where("certificates.not_after >= ?", search.NotAfterFrom.UTC())
+	}
+	if search.NotAfterTo != nil {
+		query = query.Where("certificates.not_after <= ?", search.NotAfterTo.UTC())
+	}
+	return paginate(query, pagination)
+}
+
+// toLikePattern escapes the value for the LIKE operator and replaces the * with the % wildcard.
+func toLikePattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+	return strings.ReplaceAll(value, "*", "%")
+}
+
+// ListUnparsedCertificates returns up to limit certificates stored before their columns were parsed.
+func (d *DiscoveryRepository) ListUnparsedCertificates(limit int) ([]*Certificate, error) {
+	var certificates []*Certificate
+	err := d.db.Where("key_algorithm IS NULL").Order("id").Limit(limit).Find(&certificates).Error
+	if err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// UpdateParsedColumns stores the columns parsed from the content of the certificates.
+func (d *DiscoveryRepository) UpdateParsedColumns(certificates []*Certificate) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		for _, certificate := range certificates {
+			err := tx.Model(certificate).
+				Select("fingerprint", "common_name", "sans", "issuer_dn", "not_before", "not_after", "key_algorithm", "key_size", "signature_algorithm").
+				Updates(certificate).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
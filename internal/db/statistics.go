@@ -0,0 +1,105 @@
+package db
+
+import (
+	"time"
+)
+
+// EngineStatistics are the counts of the certificates of the engine found by its latest completed discovery.
+// The expiration counts do not overlap, the certificates expiring within 30 days are not counted as expiring within 90 days.
+type EngineStatistics struct {
+	AuthorityUuid     string
+	Engine            string
+	Kind              string
+	DiscoveryUuid     string
+	DiscoveredAt      time.Time
+	Total             int64
+	Revoked           int64
+	Expired           int64
+	ExpiringIn7Days   int64
+	ExpiringIn30Days  int64
+	ExpiringIn90Days  int64
+	ExpiringIn365Days int64
+	ExpiringLater     int64
+}
+
+// KeyAlgorithmStatistics is the number of certificates of the engine with the key algorithm and size.
+type KeyAlgorithmStatistics struct {
+	Engine       string
+	KeyAlgorithm string
+	KeySize      int
+	Count        int64
+}
+
+// latestEnginesQuery selects for each engine of the authority the latest discovery which completed the discovery of the engine.
+// The discoveries with filters did not find all certificates of the engine and the discoveries which failed to list the
+// engine (engine-level failure without the serial number) did not find any, so neither of them is used for the engine.
+// The engines column is the number of engines of the discovery, the certificates stored without the engine can be
+// attributed to the engine only when it is the single engine of the discovery.
+const latestEnginesQuery = `
+	SELECT DISTINCT ON (e.engine) d.authority_uuid, e.engine, e.kind, d.id AS discovery_id, d.uuid AS discovery_uuid, d.created_at AS discovered_at,
+		(SELECT count(*) FROM discovery_engines o WHERE o.discovery_id = d.id) AS engines
+	FROM discoveries d JOIN discovery_engines e ON e.discovery_id = d.id
+	WHERE d.authority_uuid = @authority AND d.status IN ('COMPLETED', 'WARNING')
+		AND (d.meta IS NULL OR d.meta::jsonb -> 'filters' IS NULL)
+		AND NOT EXISTS (SELECT 1 FROM discovery_failures f WHERE f.discovery_id = d.id AND f.engine = e.engine AND f.serial_number = '')
+	ORDER BY e.engine, d.created_at DESC, d.id DESC`
+
+// engineCertificatesJoin matches the certificates of the discovery to the engine of latestEnginesQuery
+const engineCertificatesJoin = `c.id = dc.certificate_id AND (c.engine = l.engine OR c.engine IS NULL AND l.engines = 1)`
+
+// FindLatestCompletedDiscoveries returns the latest completed discovery of each authority, or of the given authority
+// when it is not empty.
+func (d *DiscoveryRepository) FindLatestCompletedDiscoveries(authorityUuid string) ([]Discovery, error) {
+	query := d.db.Select("DISTINCT ON (authority_uuid) *").
+		Where("status IN ?", []string{"COMPLETED", "WARNING"})
+	if authorityUuid != "" {
+		query = query.Where("authority_uuid = ?", authorityUuid)
+	}
+	var discoveries []Discovery
+	err := query.Order("authority_uuid, created_at DESC, id DESC").Find(&discoveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return discoveries, nil
+}
+
+// GetEngineStatistics returns the statistics of the engines of the authority, ordered by the engine.
+func (d *DiscoveryRepository) GetEngineStatistics(authorityUuid string) ([]EngineStatistics, error) {
+	var statistics []EngineStatistics
+	err := d.db.Raw(`
+		SELECT l.authority_uuid, l.engine, l.kind, l.discovery_uuid, l.discovered_at,
+			count(c.id) AS total,
+			count(c.id) FILTER (WHERE dc.revoked) AS revoked,
+			count(c.id) FILTER (WHERE c.not_after < now()) AS expired,
+			count(c.id) FILTER (WHERE c.not_after >= now() AND c.not_after < now() + interval '7 days') AS expiring_in7_days,
+			count(c.id) FILTER (WHERE c.not_after >= now() + interval '7 days' AND c.not_after < now() + interval '30 days') AS expiring_in30_days,
+			count(c.id) FILTER (WHERE c.not_after >= now() + interval '30 days' AND c.not_after < now() + interval '90 days') AS expiring_in90_days,
+			count(c.id) FILTER (WHERE c.not_after >= now() + interval '90 days' AND c.not_after < now() + interval '365 days') AS expiring_in365_days,
+			count(c.id) FILTER (WHERE c.not_after >= now() + interval '365 days') AS expiring_later
+		FROM (`+latestEnginesQuery+`) l
+			LEFT JOIN discovery_certificates dc ON dc.discovery_id = l.discovery_id
+			LEFT JOIN certificates c ON `+engineCertificatesJoin+`
+		GROUP BY l.authority_uuid, l.engine, l.kind, l.discovery_uuid, l.discovered_at
+		ORDER BY l.engine`, map[string]interface{}{"authority": authorityUuid}).Scan(&statistics).Error
+	if err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
+
+// GetKeyAlgorithmStatistics returns the distribution of the key algorithms and sizes of the certificates of the engines
+// of the authority, ordered by the engine and the number of certificates.
+func (d *DiscoveryRepository) GetKeyAlgorithmStatistics(authorityUuid string) ([]KeyAlgorithmStatistics, error) {
+	var statistics []KeyAlgorithmStatistics
+	err := d.db.Raw(`
+		SELECT l.engine, coalesce(c.key_algorithm, '') AS key_algorithm, coalesce(c.key_size, 0) AS key_size, count(*) AS count
+		FROM (`+latestEnginesQuery+`) l
+			JOIN discovery_certificates dc ON dc.discovery_id = l.discovery_id
+			JOIN certificates c ON `+engineCertificatesJoin+`
+		GROUP BY l.engine, coalesce(c.key_algorithm, ''), coalesce(c.key_size, 0)
+		ORDER BY l.engine, count DESC, key_algorithm, key_size`, map[string]interface{}{"authority": authorityUuid}).Scan(&statistics).Error
+	if err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
@@ -13,6 +13,7 @@ type ConnectorAttributesAPIRouter interface {
 	ListAttributeDefinitions(http.ResponseWriter, *http.Request)
 	ValidateAttributes(http.ResponseWriter, *http.Request)
 	PkiEnginesCallback(http.ResponseWriter, *http.Request)
+	KvEnginesCallback(http.ResponseWriter, *http.Request)
 }
 
 // DiscoveryAPIRouter defines the required methods for binding the api requests to a responses for the DiscoveryAPI
@@ -22,6 +23,25 @@ type DiscoveryAPIRouter interface {
 	DeleteDiscovery(http.ResponseWriter, *http.Request)
 	DiscoverCertificate(http.ResponseWriter, *http.Request)
 	GetDiscovery(http.ResponseWriter, *http.Request)
+	CancelDiscovery(http.ResponseWriter, *http.Request)
+	GetDiscoveryHistory(http.ResponseWriter, *http.Request)
+	GetDiscoveryDiff(http.ResponseWriter, *http.Request)
+	ExportDiscovery(http.ResponseWriter, *http.Request)
+	SearchCertificates(http.ResponseWriter, *http.Request)
+	ListStatistics(http.ResponseWriter, *http.Request)
+	GetStatistics(http.ResponseWriter, *http.Request)
+}
+
+// DiscoveryScheduleAPIRouter defines the required methods for binding the api requests to a responses for the DiscoveryScheduleAPI
+// The DiscoveryScheduleAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a DiscoveryScheduleAPIServicer to perform the required actions, then write the service results to the http response.
+type DiscoveryScheduleAPIRouter interface {
+	CreateSchedule(http.ResponseWriter, *http.Request)
+	ListSchedules(http.ResponseWriter, *http.Request)
+	GetSchedule(http.ResponseWriter, *http.Request)
+	UpdateSchedule(http.ResponseWriter, *http.Request)
+	DeleteSchedule(http.ResponseWriter, *http.Request)
+	ListScheduleRuns(http.ResponseWriter, *http.Request)
 }
 
 // ConnectorAttributesAPIServicer defines the api actions for the ConnectorAttributesAPI service
@@ -32,6 +52,7 @@ type ConnectorAttributesAPIServicer interface {
 	ListAttributeDefinitions(context.Context, string) (model.ImplResponse, error)
 	ValidateAttributes(context.Context, string, []model.Attribute) (model.ImplResponse, error)
 	PkiEnginesCallback(context.Context, string) (model.ImplResponse, error)
+	KvEnginesCallback(context.Context, string) (model.ImplResponse, error)
 }
 
 // DiscoveryAPIServicer defines the api actions for the DiscoveryAPI service
@@ -42,4 +63,21 @@ type DiscoveryAPIServicer interface {
 	DeleteDiscovery(context.Context, string) (model.ImplResponse, error)
 	DiscoverCertificate(context.Context, model.DiscoveryRequestDto) (model.ImplResponse, error)
 	GetDiscovery(context.Context, string, model.DiscoveryDataRequestDto) (model.ImplResponse, error)
+	CancelDiscovery(context.Context, string) (model.ImplResponse, error)
+	GetDiscoveryHistory(context.Context, string, int) (model.ImplResponse, error)
+	GetDiscoveryDiff(context.Context, string, model.DiscoveryDiffRequestDto) (model.ImplResponse, error)
+	ExportDiscovery(context.Context, string, string) (model.ImplResponse, error)
+	SearchCertificates(context.Context, model.CertificateSearchRequestDto) (model.ImplResponse, error)
+	ListStatistics(context.Context) (model.ImplResponse, error)
+	GetStatistics(context.Context, string) (model.ImplResponse, error)
+}
+
+// DiscoveryScheduleAPIServicer defines the api actions for the DiscoveryScheduleAPI service
+type DiscoveryScheduleAPIServicer interface {
+	CreateSchedule(context.Context, model.DiscoveryScheduleRequestDto) (model.ImplResponse, error)
+	ListSchedules(context.Context) (model.ImplResponse, error)
+	GetSchedule(context.Context, string) (model.ImplResponse, error)
+	UpdateSchedule(context.Context, string, model.DiscoveryScheduleRequestDto) (model.ImplResponse, error)
+	DeleteSchedule(context.Context, string) (model.ImplResponse, error)
+	ListScheduleRuns(context.Context, string, int) (model.ImplResponse, error)
 }
@@ -58,6 +58,11 @@ func (c *ConnectorAttributesAPIController) Routes() model.Routes {
 			Pattern:     "/v1/discoveryProvider/{uuid}/pkiengines/callback",
 			HandlerFunc: c.PkiEnginesCallback,
 		},
+		"KvEnginesCallback": model.Route{
+			Method:      strings.ToUpper("GET"),
+			Pattern:     "/v1/discoveryProvider/{uuid}/kvengines/callback",
+			HandlerFunc: c.KvEnginesCallback,
+		},
 	}
 }
 
@@ -81,6 +86,26 @@ func (c *ConnectorAttributesAPIController) PkiEnginesCallback(w http.ResponseWri
 	}
 }
 
+func (c *ConnectorAttributesAPIController) KvEnginesCallback(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	authorityUuid := params["uuid"]
+	if authorityUuid == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "authorityUuid"}, nil)
+		return
+	}
+	result, err := c.service.KvEnginesCallback(r.Context(), authorityUuid)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
 // ListAttributeDefinitions - List available Attributes
 func (c *ConnectorAttributesAPIController) ListAttributeDefinitions(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
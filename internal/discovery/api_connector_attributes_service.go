@@ -57,6 +57,11 @@ func (s *ConnectorAttributesAPIService) ListAttributeDefinitions(ctx context.Con
 	attributes = append(attributes, attribute)
 	attribute = model.GetAttributeDefByUUID(model.DISCOVERY_PKI_ENGINE_ATTR).(model.DataAttribute)
 	attributes = append(attributes, attribute)
+	for _, definition := range model.GetAttributeListBySet(model.DisoveryAttributes) {
+		if definition.GetUuid() != model.DISCOVERY_AUTHORITY_ATTR && definition.GetUuid() != model.DISCOVERY_PKI_ENGINE_ATTR {
+			attributes = append(attributes, definition)
+		}
+	}
 	return model.Response(http.StatusOK, attributes), nil
 
 }
@@ -75,6 +80,12 @@ func (s *ConnectorAttributesAPIService) ValidateAttributes(ctx context.Context,
 	if auth == nil {
 		return model.Response(422, []string{"Authority not found"}), nil
 	}
+	if _, err := newCertificateFilter(requestAttributeDto); err != nil {
+		return model.Response(422, []string{err.Error()}), nil
+	}
+	if _, err := newKvOptions(requestAttributeDto); err != nil {
+		return model.Response(422, []string{err.Error()}), nil
+	}
 	return model.Response(http.StatusOK, nil), nil
 
 }
@@ -112,3 +123,35 @@ func (s *ConnectorAttributesAPIService) PkiEnginesCallback(ctx context.Context,
 	}
 	return model.Response(http.StatusOK, engineList), nil
 }
+
+func (s *ConnectorAttributesAPIService) KvEnginesCallback(ctx context.Context, authorityUuid string) (model.ImplResponse, error) {
+	authority, err := s.authorityRepo.FindAuthorityInstanceByUUID(authorityUuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{
+			Message: "Authority not found",
+		}), nil
+	}
+	client, err := vault.GetClient(*authority)
+	if err != nil {
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+			Message: "Failed to create vault client",
+		}), err
+	}
+	engines, err := listKvEngines(ctx, client)
+	if err != nil {
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{
+			Message: "Failed to list KV secrets engines",
+		}), nil
+	}
+	var engineList []model.AttributeContent
+	for engineName, version := range engines {
+		engineDataObject := make(map[string]interface{})
+		engineDataObject["engineName"] = engineName
+		engineDataObject["version"] = version
+		engineList = append(engineList, model.ObjectAttributeContent{
+			Reference: engineName,
+			Data:      engineDataObject,
+		})
+	}
+	return model.Response(http.StatusOK, engineList), nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -58,6 +59,41 @@ func (c *DiscoveryAPIController) Routes() model.Routes {
 			Pattern:     "/v1/discoveryProvider/discover/{uuid}",
 			HandlerFunc: c.GetDiscovery,
 		},
+		"CancelDiscovery": model.Route{
+			Method:      strings.ToUpper("Post"),
+			Pattern:     "/v1/discoveryProvider/discover/{uuid}/cancel",
+			HandlerFunc: c.CancelDiscovery,
+		},
+		"GetDiscoveryHistory": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/discover/{uuid}/history",
+			HandlerFunc: c.GetDiscoveryHistory,
+		},
+		"GetDiscoveryDiff": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/discover/{uuid}/diff",
+			HandlerFunc: c.GetDiscoveryDiff,
+		},
+		"ExportDiscovery": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/discover/{uuid}/export",
+			HandlerFunc: c.ExportDiscovery,
+		},
+		"SearchCertificates": model.Route{
+			Method:      strings.ToUpper("Post"),
+			Pattern:     "/v1/discoveryProvider/certificates/search",
+			HandlerFunc: c.SearchCertificates,
+		},
+		"ListStatistics": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/statistics",
+			HandlerFunc: c.ListStatistics,
+		},
+		"GetStatistics": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/statistics/{authorityUuid}",
+			HandlerFunc: c.GetStatistics,
+		},
 	}
 }
 
@@ -82,6 +118,187 @@ func (c *DiscoveryAPIController) DeleteDiscovery(w http.ResponseWriter, r *http.
 	}
 }
 
+// CancelDiscovery - Cancel running Discovery
+func (c *DiscoveryAPIController) CancelDiscovery(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	uuidParam := params["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	result, err := c.service.CancelDiscovery(r.Context(), uuidParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
+// GetDiscoveryHistory - List runs of the Discovery with the same name
+func (c *DiscoveryAPIController) GetDiscoveryHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	uuidParam := params["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	limitParam := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
+			return
+		}
+		limitParam = limit
+	}
+	result, err := c.service.GetDiscoveryHistory(r.Context(), uuidParam, limitParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
+// GetDiscoveryDiff - Compare the Discovery with the previous one
+func (c *DiscoveryAPIController) GetDiscoveryDiff(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	uuidParam := params["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	query := r.URL.Query()
+	requestParam := model.DiscoveryDiffRequestDto{BaseUuid: query.Get("base")}
+	for _, change := range strings.Split(query.Get("change"), ",") {
+		if change = strings.TrimSpace(change); change != "" {
+			requestParam.Changes = append(requestParam.Changes, change)
+		}
+	}
+	var err error
+	for name, target := range map[string]*int64{"pageNumber": &requestParam.PageNumber, "itemsPerPage": &requestParam.ItemsPerPage} {
+		if value := query.Get(name); value != "" {
+			if *target, err = strconv.ParseInt(value, 10, 64); err != nil {
+				c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
+				return
+			}
+		}
+	}
+	if value := query.Get("export"); value != "" {
+		if requestParam.Export, err = strconv.ParseBool(value); err != nil {
+			c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
+			return
+		}
+	}
+	result, err := c.service.GetDiscoveryDiff(r.Context(), uuidParam, requestParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	if diff, ok := result.Body.(model.DiscoveryDiffDto); ok && requestParam.Export {
+		w.Header().Set("Content-Disposition", "attachment; filename=\"discovery-diff-"+diff.BaseUuid+"-"+diff.Uuid+".json\"")
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
+// ExportDiscovery - Export all certificates of the Discovery
+func (c *DiscoveryAPIController) ExportDiscovery(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	uuidParam := params["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	formatParam := r.URL.Query().Get("format")
+	if formatParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "format"}, nil)
+		return
+	}
+	result, err := c.service.ExportDiscovery(r.Context(), uuidParam, formatParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
+// SearchCertificates - Search the discovered certificates
+func (c *DiscoveryAPIController) SearchCertificates(w http.ResponseWriter, r *http.Request) {
+	certificateSearchRequestDtoParam := model.CertificateSearchRequestDto{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&certificateSearchRequestDtoParam); err != nil {
+		c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.SearchCertificates(r.Context(), certificateSearchRequestDtoParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
+// ListStatistics - List certificate statistics of the authorities with completed Discovery
+func (c *DiscoveryAPIController) ListStatistics(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ListStatistics(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
+// GetStatistics - Get certificate statistics of the authority
+func (c *DiscoveryAPIController) GetStatistics(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	authorityUuidParam := params["authorityUuid"]
+	if authorityUuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "authorityUuid"}, nil)
+		return
+	}
+	result, err := c.service.GetStatistics(r.Context(), authorityUuidParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	err = model.EncodeJSONResponse(result.Body, &result.Code, w)
+	if err != nil {
+		return
+	}
+}
+
 // DiscoverCertificate - Initiate certificate Discovery
 func (c *DiscoveryAPIController) DiscoverCertificate(w http.ResponseWriter, r *http.Request) {
 	discoveryRequestDtoParam := model.DiscoveryRequestDto{}
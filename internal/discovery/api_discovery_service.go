@@ -6,12 +6,16 @@ import (
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/vault"
 	"context"
-	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	vault2 "github.com/hashicorp/vault-client-go"
 	"github.com/yuseferi/zax/v2"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // DiscoveryAPIService is a service that implements the logic for the DiscoveryAPIServicer
@@ -20,14 +24,23 @@ import (
 type DiscoveryAPIService struct {
 	discoveryRepo *db.DiscoveryRepository
 	authorityRepo *db.AuthorityRepository
+	runner        *Runner
+	batchSize     int
+	gracePeriod   time.Duration
+	statistics    *statisticsCache
 	log           *zap.Logger
 }
 
-// NewDiscoveryAPIService creates a default api service
-func NewDiscoveryAPIService(discoveryRepo *db.DiscoveryRepository, authorityRepo *db.AuthorityRepository, logger *zap.Logger) DiscoveryAPIServicer {
+// NewDiscoveryAPIService creates a default api service. The discovery in progress which did not store its progress
+// for the grace period is considered interrupted.
+func NewDiscoveryAPIService(discoveryRepo *db.DiscoveryRepository, authorityRepo *db.AuthorityRepository, runner *Runner, batchSize int, gracePeriod time.Duration, logger *zap.Logger) *DiscoveryAPIService {
 	return &DiscoveryAPIService{
 		discoveryRepo: discoveryRepo,
 		authorityRepo: authorityRepo,
+		runner:        runner,
+		batchSize:     max(batchSize, 1),
+		gracePeriod:   gracePeriod,
+		statistics:    newStatisticsCache(),
 		log:           logger,
 	}
 }
@@ -39,6 +52,21 @@ func (s *DiscoveryAPIService) DeleteDiscovery(ctx context.Context, uuid string)
 		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + uuid + " not found."}), nil
 	}
 
+	// running discovery is stopped first, so that it does not store certificates of the deleted discovery
+	if discovery.Status == "IN_PROGRESS" && !s.runner.Running(discovery.UUID) {
+		claimed, err := s.claimOrCancelDiscovery(discovery)
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Unable to stop discovery", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to delete discovery " + discovery.UUID}), nil
+		}
+		if !claimed {
+			return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Discovery " + discovery.UUID + " is running on another instance, it is being cancelled."}), nil
+		}
+	}
+	if err := s.runner.Stop(ctx, discovery.UUID, errDiscoveryDeleted); err != nil {
+		return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Discovery " + discovery.UUID + " is still running."}), nil
+	}
+
 	s.log.With(zax.Get(ctx)...).Info("Deleting discovery", zap.String("discovery_uuid", discovery.UUID))
 	err = s.discoveryRepo.DeleteDiscovery(discovery)
 	if err != nil {
@@ -50,174 +78,516 @@ func (s *DiscoveryAPIService) DeleteDiscovery(ctx context.Context, uuid string)
 
 // DiscoverCertificate - Initiate certificate Discovery
 func (s *DiscoveryAPIService) DiscoverCertificate(ctx context.Context, discoveryRequestDto model.DiscoveryRequestDto) (model.ImplResponse, error) {
+	return s.startDiscovery(ctx, discoveryRequestDto, nil), nil
+}
+
+// startDiscovery creates the discovery and runs it in the background. The schedule is set for the discoveries
+// started by the schedule.
+func (s *DiscoveryAPIService) startDiscovery(ctx context.Context, discoveryRequestDto model.DiscoveryRequestDto, schedule *db.DiscoverySchedule) model.ImplResponse {
 	response := model.DiscoveryProviderDto{
-		Uuid:                        utils.DeterministicGUID(discoveryRequestDto.Name),
+		Uuid:                        utils.GenerateRandomUUID(),
 		Name:                        discoveryRequestDto.Name,
 		Status:                      model.IN_PROGRESS,
 		TotalCertificatesDiscovered: 0,
 		CertificateData:             nil,
 		Meta:                        nil,
 	}
+	filter, err := newCertificateFilter(discoveryRequestDto.Attributes)
+	if err != nil {
+		return model.Response(http.StatusUnprocessableEntity, []string{err.Error()})
+	}
+	kv, err := newKvOptions(discoveryRequestDto.Attributes)
+	if err != nil {
+		return model.Response(http.StatusUnprocessableEntity, []string{err.Error()})
+	}
+	options := discoveryOptions{
+		PartialResults: getBooleanAttribute(model.DISCOVERY_PARTIAL_RESULTS_ATTR, discoveryRequestDto.Attributes),
+		IncludeIssuers: getBooleanAttribute(model.DISCOVERY_INCLUDE_ISSUERS_ATTR, discoveryRequestDto.Attributes),
+		Incremental:    getBooleanAttribute(model.DISCOVERY_INCREMENTAL_ATTR, discoveryRequestDto.Attributes),
+		Kv:             kv,
+	}
+	if !filter.isEmpty() {
+		options.Filters = filter
+	}
+	meta, _ := json.Marshal(options)
 	discovery := &db.Discovery{
 		UUID:         response.Uuid,
 		Name:         response.Name,
 		Status:       string(response.Status),
-		Meta:         nil,
+		Meta:         meta,
 		Certificates: nil,
 	}
+	if schedule != nil {
+		discovery.ScheduleId = &schedule.Id
+	}
 
-	uuid := model.GetAttributeFromArrayByUUID(model.DISCOVERY_AUTHORITY_ATTR, discoveryRequestDto.Attributes).GetContent()[0].GetData().(map[string]interface{})["uuid"].(string)
+	uuid := getAuthorityUuid(discoveryRequestDto.Attributes)
+	if uuid == "" {
+		return model.Response(http.StatusUnprocessableEntity, []string{"Authority is required"})
+	}
 
 	authority, err := s.authorityRepo.FindAuthorityInstanceByUUID(uuid)
 	if err != nil {
-		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Authority not found " + uuid}), nil
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Authority not found " + uuid})
 	}
+	discovery.AuthorityUuid = authority.UUID
 
 	enginesAttr := model.GetAttributeFromArrayByUUID(model.DISCOVERY_PKI_ENGINE_ATTR, discoveryRequestDto.Attributes)
 	var enginesList []string
-	if enginesAttr == nil {
+	if enginesAttr == nil && kv != nil {
+		// only the KV secrets engines are searched
+		enginesList = make([]string, 0)
+	} else if enginesAttr == nil {
 		s.log.With(zax.Get(ctx)...).Info("No PKI engines specified for discovery, trying to get all available engines")
 		// get the vault client
 		client, err := vault.GetClient(*authority)
 		if err != nil {
-			discovery.Status = "FAILED"
-			err := s.discoveryRepo.UpdateDiscovery(discovery)
-			if err != nil {
-				s.log.With(zax.Get(ctx)...).Error(err.Error())
-			}
-			s.log.With(zax.Get(ctx)...).Error(err.Error())
-			return model.Response(http.StatusBadRequest, model.ErrorMessageDto{Message: "Unable to create vault client"}), nil
+			s.failDiscovery(ctx, discovery, err)
+			return model.Response(http.StatusBadRequest, model.ErrorMessageDto{Message: "Unable to create vault client"})
 		}
-		ctx := context.Background()
 		//Due to the nature of its intended usage, there is no guarantee on backwards compatibility for this endpoint.
-		mounts, _ := client.System.InternalUiListEnabledVisibleMounts(ctx)
+		mounts, err := client.System.InternalUiListEnabledVisibleMounts(context.Background())
+		if err != nil {
+			s.failDiscovery(ctx, discovery, err)
+			return model.Response(http.StatusBadGateway, model.ErrorMessageDto{Message: "Unable to list secrets engines of authority " + authority.UUID})
+		}
 		for engineName, engineData := range mounts.Data.Secret {
 			engineName = strings.TrimSuffix(engineName, "/")
-			if engineData.(map[string]any)["type"] == "pki" {
+			if data, ok := engineData.(map[string]any); ok && data["type"] == "pki" {
 				enginesList = append(enginesList, engineName)
 			}
 		}
 	} else {
 		enginesList = make([]string, 0)
 		for _, engine := range enginesAttr.GetContent() {
-			engineData := engine.(model.ObjectAttributeContent).GetData().(map[string]interface{})
-			engineName := engineData["engineName"].(string)
-			enginesList = append(enginesList, engineName)
+			engineData, _ := engine.GetData().(map[string]interface{})
+			if engineName, ok := engineData["engineName"].(string); ok && engineName != "" {
+				enginesList = append(enginesList, engineName)
+			}
 		}
 	}
 
+	heartbeatAt := time.Now()
+	discovery.HeartbeatAt = &heartbeatAt
+	for _, engine := range enginesList {
+		discovery.Engines = append(discovery.Engines, db.DiscoveryEngine{Engine: engine, Kind: db.ENGINE_KIND_PKI})
+	}
+	if kv != nil {
+		for _, engine := range kv.Engines {
+			discovery.Engines = append(discovery.Engines, db.DiscoveryEngine{Engine: engine, Kind: db.ENGINE_KIND_KV})
+		}
+	}
 	err = s.discoveryRepo.CreateDiscovery(discovery)
 	if err != nil {
-		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Unable to create discovery " + discovery.UUID}), nil
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Unable to create discovery " + discovery.UUID})
 	}
 
 	s.log.With(zax.Get(ctx)...).Info("Starting discovery of certificates", zap.String("discovery_uuid", discovery.UUID), zap.String("authority_uuid", authority.UUID))
-	go s.DiscoveryCertificates(ctx, authority, discovery, enginesList)
+	fields := zax.Get(ctx)
+	s.runner.Go(discovery.UUID, func(runCtx context.Context) {
+		s.DiscoveryCertificates(zax.Set(runCtx, fields), authority, discovery)
+	})
 
-	return model.Response(http.StatusOK, response), nil
+	return model.Response(http.StatusOK, response)
+}
+
+// failDiscovery stores the discovery which could not be started as FAILED.
+func (s *DiscoveryAPIService) failDiscovery(ctx context.Context, discovery *db.Discovery, cause error) {
+	s.log.With(zax.Get(ctx)...).Error("Unable to start discovery", zap.String("discovery_uuid", discovery.UUID), zap.Error(cause))
+	discovery.Status = "FAILED"
+	if err := s.discoveryRepo.UpdateDiscovery(discovery); err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to store failed discovery", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+	}
+}
+
+// CancelDiscovery - Cancel running Discovery
+func (s *DiscoveryAPIService) CancelDiscovery(ctx context.Context, uuid string) (model.ImplResponse, error) {
+	discovery, err := s.discoveryRepo.FindDiscoveryByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + uuid + " not found."}), nil
+	}
+	if discovery.Status != "IN_PROGRESS" {
+		return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Discovery " + uuid + " is not running."}), nil
+	}
+
+	s.log.With(zax.Get(ctx)...).Info("Cancelling discovery", zap.String("discovery_uuid", discovery.UUID))
+	if !s.runner.Running(discovery.UUID) {
+		claimed, err := s.claimOrCancelDiscovery(discovery)
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Unable to cancel discovery", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to cancel discovery " + uuid}), nil
+		}
+		if claimed {
+			s.updateDiscoveryStatus(ctx, discovery, "CANCELLED")
+			return model.Response(http.StatusNoContent, nil), nil
+		}
+		return model.Response(http.StatusAccepted, nil), nil
+	}
+	if err := s.runner.Stop(ctx, discovery.UUID, errDiscoveryCancelled); err != nil {
+		return model.Response(http.StatusAccepted, nil), nil
+	}
+	return model.Response(http.StatusNoContent, nil), nil
+}
+
+// claimOrCancelDiscovery takes over the discovery in progress which is not running on this instance and returns true,
+// when it did not store its progress for the grace period, for example after restart of the connector. Otherwise
+// the discovery is running on another instance, which is requested to cancel it on the next progress.
+func (s *DiscoveryAPIService) claimOrCancelDiscovery(discovery *db.Discovery) (bool, error) {
+	claimed, err := s.discoveryRepo.ClaimStaleDiscovery(discovery, time.Now().Add(-s.gracePeriod))
+	if err != nil || claimed {
+		return claimed, err
+	}
+	return false, s.discoveryRepo.RequestDiscoveryCancel(discovery)
 }
 
 // GetDiscovery - Get Discovery status and result
 func (s *DiscoveryAPIService) GetDiscovery(ctx context.Context, uuid string, discoveryDataRequestDto model.DiscoveryDataRequestDto) (model.ImplResponse, error) {
+	pagination, errs := getPagination(discoveryDataRequestDto)
+	if len(errs) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, errs), nil
+	}
 	discovery, err := s.discoveryRepo.FindDiscoveryByUUID(uuid)
 	if err != nil {
 		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + uuid + " not found."}), nil
 	}
 	if discovery.Status == "IN_PROGRESS" {
-		return model.Response(http.StatusOK, model.DiscoveryProviderDto{Uuid: discovery.UUID, Name: discovery.Name, Status: model.IN_PROGRESS, TotalCertificatesDiscovered: 0, CertificateData: nil, Meta: nil}), nil
-	} else {
-		pagination := db.Pagination{
-			Page:  int(discoveryDataRequestDto.PageNumber),
-			Limit: int(discoveryDataRequestDto.ItemsPerPage),
-		}
-		result, _ := s.discoveryRepo.List(pagination, discovery)
-		var certificateDtos []model.DiscoveryProviderCertificateDataDto
-		rows, _ := result.Rows.([]*db.Certificate)
-		for _, certificateData := range rows {
-			discoveryProviderCertificateDataDto := model.DiscoveryProviderCertificateDataDto{
-				Uuid:          certificateData.UUID,
-				Base64Content: certificateData.Base64Content,
+		response := model.DiscoveryProviderDto{Uuid: discovery.UUID, Name: discovery.Name, Status: model.IN_PROGRESS, TotalCertificatesDiscovered: int64(discovery.CertificatesRead), CertificateData: nil, Meta: getProgressMetadata(discovery)}
+		if getDiscoveryOptions(discovery).PartialResults {
+			certificateDtos, page, err := s.listDiscoveryCertificates(discovery, pagination)
+			if err != nil {
+				s.log.With(zax.Get(ctx)...).Error("Unable to list discovered certificates", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+				return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to list certificates of discovery " + discovery.UUID}), nil
+			}
+			response.CertificateData = certificateDtos
+			if cursorMeta := getNextCursorMetadata(page); cursorMeta != nil {
+				response.Meta = append(response.Meta, *cursorMeta)
 			}
-			certificateDtos = append(certificateDtos, discoveryProviderCertificateDataDto)
 		}
-
-		return model.Response(http.StatusOK, model.DiscoveryProviderDto{Uuid: discovery.UUID, Name: discovery.Name, Status: model.COMPLETED, TotalCertificatesDiscovered: result.TotalRows, CertificateData: certificateDtos, Meta: nil}), nil
+		return model.Response(http.StatusOK, response), nil
+	} else {
+		certificateDtos, page, err := s.listDiscoveryCertificates(discovery, pagination)
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Unable to list discovered certificates", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to list certificates of discovery " + discovery.UUID}), nil
+		}
+		meta := getProgressMetadata(discovery)
+		if filtersMeta := getDiscoveryOptions(discovery).Filters.toMetadata(); filtersMeta != nil {
+			meta = append(meta, *filtersMeta)
+		}
+		if failuresMeta := s.getFailuresMetadata(ctx, discovery); failuresMeta != nil {
+			meta = append(meta, *failuresMeta)
+		}
+		if reasonMeta := getStatusReasonMetadata(discovery); reasonMeta != nil {
+			meta = append(meta, *reasonMeta)
+		}
+		if cursorMeta := getNextCursorMetadata(page); cursorMeta != nil {
+			meta = append(meta, *cursorMeta)
+		}
+		return model.Response(http.StatusOK, model.DiscoveryProviderDto{Uuid: discovery.UUID, Name: discovery.Name, Status: getDiscoveryStatus(discovery.Status), TotalCertificatesDiscovered: page.TotalRows, CertificateData: certificateDtos, Meta: meta}), nil
 	}
 
 }
 
-func (s *DiscoveryAPIService) DiscoveryCertificates(ctx context.Context, authority *db.AuthorityInstance, discovery *db.Discovery, list []string) {
+// listDiscoveryCertificates returns the requested page of certificates stored by the discovery with the total number of them.
+func (s *DiscoveryAPIService) listDiscoveryCertificates(discovery *db.Discovery, pagination db.Pagination) ([]model.DiscoveryProviderCertificateDataDto, *db.Pagination, error) {
+	result, err := s.discoveryRepo.List(pagination, discovery)
+	if err != nil {
+		return nil, nil, err
+	}
+	var certificateDtos []model.DiscoveryProviderCertificateDataDto
+	rows, _ := result.Rows.([]*db.Certificate)
+	for _, certificateData := range rows {
+		discoveryProviderCertificateDataDto := model.DiscoveryProviderCertificateDataDto{
+			Uuid:          certificateData.UUID,
+			Base64Content: certificateData.Base64Content,
+		}
+		if meta, ok := getCertificateMeta(certificateData); ok {
+			discoveryProviderCertificateDataDto.Meta = meta.toMetadata()
+		}
+		certificateDtos = append(certificateDtos, discoveryProviderCertificateDataDto)
+	}
+	return certificateDtos, result, nil
+}
+
+// DiscoveryCertificates discovers the certificates of the engines of the discovery which are not done yet. Certificates
+// are read by a pool of workers limited by the runner and stored in batches as they are read. Each engine is marked
+// done when its certificates are stored, so that the interrupted discovery can continue with the remaining engines.
+func (s *DiscoveryAPIService) DiscoveryCertificates(ctx context.Context, authority *db.AuthorityInstance, discovery *db.Discovery) {
 	// get the vault client
 	client, err := vault.GetClient(*authority)
 	if err != nil {
-		discovery.Status = "FAILED"
-		err := s.discoveryRepo.UpdateDiscovery(discovery)
-		if err != nil {
-			s.log.With(zax.Get(ctx)...).Error(err.Error())
-		}
 		s.log.With(zax.Get(ctx)...).Error(err.Error())
+		s.updateDiscoveryStatus(ctx, discovery, "FAILED")
+		return
+	}
+
+	options := getDiscoveryOptions(discovery)
+	filter := options.Filters
+	if filter == nil {
+		filter = &certificateFilter{}
+	}
+	if err := filter.compile(); err != nil {
+		s.log.With(zax.Get(ctx)...).Error(err.Error())
+		s.updateDiscoveryStatus(ctx, discovery, "FAILED")
+		return
+	}
+
+	engines, err := s.discoveryRepo.ListDiscoveryEngines(discovery)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to list engines of discovery", zap.Error(err))
+		s.updateDiscoveryStatus(ctx, discovery, "FAILED")
 		return
 	}
+	enginesDone := 0
+	for _, engine := range engines {
+		if engine.Done {
+			enginesDone++
+		}
+	}
 
-	if len(list) == 0 {
+	kvEngines := make(map[string]int)
+	if options.Kv != nil {
+		kvEngines, err = listKvEngines(ctx, client)
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Unable to list KV secrets engines", zap.Error(err))
+			s.updateDiscoveryStatus(ctx, discovery, "FAILED")
+			return
+		}
+	}
+
+	writer := newCertificateWriter(s.discoveryRepo, discovery, s.batchSize)
+	if options.Incremental {
+		writer.rememberSerials(authority.UUID)
+	}
+	p := newProgress(len(engines))
+	if enginesDone > 0 {
+		s.log.With(zax.Get(ctx)...).Info("Resuming discovery", zap.String("discovery_uuid", discovery.UUID), zap.Int("engines_done", enginesDone), zap.Int("engines_total", len(engines)))
+		p.restore(discovery, enginesDone)
+	}
+	stopProgress := s.trackProgress(ctx, discovery, p)
+	if len(engines) == 0 {
 		s.log.With(zax.Get(ctx)...).Info("No PKI engines available for discovery")
-	} else {
-		for _, engine := range list {
-			s.log.With(zax.Get(ctx)...).Info("Discovering certificates", zap.String("engine", engine))
-			certificates, err := client.Secrets.PkiListCerts(ctx, vault2.WithMountPath(engine))
-			if err != nil {
-				discovery.Status = "FAILED"
-				err := s.discoveryRepo.UpdateDiscovery(discovery)
-				if err != nil {
-					s.log.With(zax.Get(ctx)...).Error(err.Error())
-				}
-				return
+	}
+	for i := range engines {
+		engine := &engines[i]
+		if engine.Done {
+			continue
+		}
+		err = s.discoverDiscoveryEngine(ctx, client, authority, engine, options, filter, kvEngines, writer, p)
+		if err == nil {
+			err = writer.Flush(ctx)
+		}
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Error discovering certificates", zap.String("engine", engine.Engine), zap.Error(err))
+			break
+		}
+		p.enginesDone.Add(1)
+		if err := s.discoveryRepo.CompleteDiscoveryEngine(engine); err != nil {
+			// the engine is searched again when the discovery is resumed
+			s.log.With(zax.Get(ctx)...).Warn("Unable to store engine checkpoint", zap.String("engine", engine.Engine), zap.Error(err))
+		}
+	}
+	if closeErr := writer.Close(); err == nil && closeErr != nil {
+		s.log.With(zax.Get(ctx)...).Error(closeErr.Error())
+		err = closeErr
+	}
+	stopProgress()
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errShutdown) {
+			// the discovery stays in progress to be resumed or failed on the next start of the connector
+			if err := s.discoveryRepo.ReleaseDiscovery(discovery); err != nil {
+				s.log.With(zax.Get(ctx)...).Error(err.Error())
+			}
+			s.log.With(zax.Get(ctx)...).Info("Discovery interrupted by shutdown", zap.String("discovery_uuid", discovery.UUID))
+			return
+		}
+		s.updateDiscoveryStatus(ctx, discovery, getInterruptedStatus(ctx))
+		return
+	}
+
+	failures, err := s.discoveryRepo.CountDiscoveryFailures(discovery)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to count discovery failures", zap.Error(err))
+	}
+	if failures > 0 {
+		// some engines or certificates were not discovered, the failures are reported in the discovery meta
+		s.updateDiscoveryStatus(ctx, discovery, "WARNING")
+		s.log.With(zax.Get(ctx)...).Warn("Discovery completed with failures", zap.String("discovery_uuid", discovery.UUID), zap.String("authority_uuid", authority.UUID), zap.Int("total_certificates", writer.Written()), zap.Int64("failed_engines", p.enginesFailed.Load()), zap.Int64("failed_certificates", p.failed.Load()))
+		return
+	}
+
+	// Update discovery status to "COMPLETED"
+	s.updateDiscoveryStatus(ctx, discovery, "COMPLETED")
+	s.log.With(zax.Get(ctx)...).Info("Discovery completed", zap.String("discovery_uuid", discovery.UUID), zap.String("authority_uuid", authority.UUID), zap.Int("total_certificates", writer.Written()+int(p.known.Load())), zap.Int64("skipped_certificates", p.skipped.Load()))
+}
+
+// discoverDiscoveryEngine searches the PKI or KV secrets engine of the discovery.
+func (s *DiscoveryAPIService) discoverDiscoveryEngine(ctx context.Context, client *vault2.Client, authority *db.AuthorityInstance, engine *db.DiscoveryEngine, options discoveryOptions, filter *certificateFilter, kvEngines map[string]int, writer *certificateWriter, p *progress) error {
+	if engine.Kind != db.ENGINE_KIND_KV {
+		s.log.With(zax.Get(ctx)...).Info("Discovering certificates", zap.String("engine", engine.Engine))
+		return s.discoverEngine(ctx, client, authority, engine.Engine, options, filter, writer, p)
+	}
+	version, ok := kvEngines[engine.Engine]
+	if !ok || options.Kv == nil {
+		s.log.With(zax.Get(ctx)...).Warn("KV secrets engine not found", zap.String("engine", engine.Engine))
+		p.enginesFailed.Add(1)
+		s.recordFailure(ctx, writer.discovery, engine.Engine, "", fmt.Errorf("KV secrets engine not found"))
+		return nil
+	}
+	s.log.With(zax.Get(ctx)...).Info("Discovering certificates in secrets", zap.String("engine", engine.Engine), zap.Int("version", version))
+	return s.discoverKvEngine(ctx, client, authority, engine.Engine, version, options.Kv, filter, writer, p)
+}
+
+// discoverEngine lists the certificates of the engine and reads them in parallel by the workers of the runner.
+func (s *DiscoveryAPIService) discoverEngine(ctx context.Context, client *vault2.Client, authority *db.AuthorityInstance, engine string, options discoveryOptions, filter *certificateFilter, writer *certificateWriter, p *progress) error {
+	release, err := s.runner.Acquire(ctx, authority.UUID)
+	if err != nil {
+		return err
+	}
+	certificates, err := client.Secrets.PkiListCerts(ctx, vault2.WithMountPath(engine))
+	release()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// engine which can not be listed is reported and the discovery continues with the next engine
+		s.log.With(zax.Get(ctx)...).Warn("Error listing certificates", zap.String("engine", engine), zap.Error(err))
+		p.enginesFailed.Add(1)
+		s.recordFailure(ctx, writer.discovery, engine, "", err)
+		return nil
+	}
+	p.enginesListed.Add(1)
+	p.listed.Add(int64(len(certificates.Data.Keys)))
+
+	revoked, err := s.listRevokedCertificates(ctx, client, authority.UUID, engine)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// revocation of the certificates is still known from the revocation time of the read certificate
+		s.log.With(zax.Get(ctx)...).Warn("Error listing revoked certificates", zap.String("engine", engine), zap.Error(err))
+		s.recordFailure(ctx, writer.discovery, engine, "", fmt.Errorf("unable to list revoked certificates: %v", err))
+	}
+
+	issuers, err := s.resolveIssuers(ctx, client, authority.UUID, engine, filter)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// certificates can not be matched by their issuer, the engine is reported and skipped
+		s.log.With(zax.Get(ctx)...).Warn("Error listing issuers", zap.String("engine", engine), zap.Error(err))
+		p.enginesFailed.Add(1)
+		s.recordFailure(ctx, writer.discovery, engine, "", fmt.Errorf("unable to list issuers: %v", err))
+		return nil
+	}
+
+	keys := certificates.Data.Keys
+	if options.Incremental {
+		// only the certificates not read by the previous incremental discovery are read from the engine
+		keys, err = s.discoverKnownCertificates(ctx, authority.UUID, engine, keys, revoked, filter, issuers, writer, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	queue := make(chan string)
+	g.Go(func() error {
+		defer close(queue)
+		for _, certificateKey := range keys {
+			select {
+			case queue <- certificateKey:
+			case <-gctx.Done():
+				return gctx.Err()
 			}
-			var certificateKeys []*db.Certificate
-			for _, certificateKey := range certificates.Data.Keys {
+		}
+		return nil
+	})
+	for i := 0; i < s.runner.Workers(); i++ {
+		g.Go(func() error {
+			for certificateKey := range queue {
 				s.log.With(zax.Get(ctx)...).Debug("Reading certificate", zap.String("certificate_key", certificateKey), zap.String("engine", engine))
-				certificateData, err := client.Secrets.PkiReadCert(ctx, certificateKey, vault2.WithMountPath(engine))
+				release, err := s.runner.Acquire(gctx, authority.UUID)
 				if err != nil {
-					discovery.Status = "FAILED"
-					s.log.With(zax.Get(ctx)...).Error("Error reading certificate", zap.String("certificate_key", certificateKey), zap.String("engine", engine), zap.Error(err))
-					err := s.discoveryRepo.UpdateDiscovery(discovery)
-					if err != nil {
-						s.log.With(zax.Get(ctx)...).Error(err.Error())
-					}
-
-					return
+					return err
 				}
-				certificate := db.Certificate{
-					SerialNumber:  certificateKey,
-					UUID:          utils.DeterministicGUID(certificateKey),
-					Base64Content: base64.StdEncoding.EncodeToString([]byte(certificateData.Data.Certificate)),
+				certificateData, err := client.Secrets.PkiReadCert(gctx, certificateKey, vault2.WithMountPath(engine))
+				release()
+				if err != nil {
+					if gctx.Err() != nil {
+						return gctx.Err()
+					}
+					// certificate which can not be read is reported and the discovery continues
+					p.failed.Add(1)
+					s.log.With(zax.Get(ctx)...).Warn("Error reading certificate", zap.String("certificate_key", certificateKey), zap.String("engine", engine), zap.Error(err))
+					s.recordFailure(ctx, writer.discovery, engine, certificateKey, err)
+					continue
 				}
-				certificateKeys = append(certificateKeys, &certificate)
-			}
-			err = s.discoveryRepo.AssociateCertificatesToDiscovery(discovery, certificateKeys...)
-			if err != nil {
-				discovery.Status = "FAILED"
-				s.log.With(zax.Get(ctx)...).Error(err.Error())
-				err := s.discoveryRepo.UpdateDiscovery(discovery)
+				discovered, err := newDiscoveredCertificate(engine, certificateKey, certificateData.Data, revoked[certificateKey])
 				if err != nil {
-					s.log.With(zax.Get(ctx)...).Error(err.Error())
+					p.failed.Add(1)
+					s.log.With(zax.Get(ctx)...).Warn("Error parsing certificate", zap.String("certificate_key", certificateKey), zap.String("engine", engine), zap.Error(err))
+					s.recordFailure(ctx, writer.discovery, engine, certificateKey, err)
+					continue
+				}
+				p.read.Add(1)
+				if !filter.matches(discovered, issuers) {
+					p.skipped.Add(1)
+					continue
+				}
+				if err := s.writeDiscoveredCertificate(gctx, engine, discovered, writer); err != nil {
+					return err
 				}
-				return
 			}
-		}
+			return nil
+		})
 	}
-	// Update discovery status to "COMPLETED"
-	discovery.Status = "COMPLETED"
-	err = s.discoveryRepo.UpdateDiscovery(discovery)
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	if options.IncludeIssuers {
+		// issuers are stored after the certificates, so that the issuer details are kept in the metadata
+		return s.discoverIssuers(ctx, client, authority.UUID, engine, filter, issuers, writer, p)
+	}
+	return nil
+}
+
+// recordFailure stores the reason why the engine or the certificate of the engine was not discovered.
+func (s *DiscoveryAPIService) recordFailure(ctx context.Context, discovery *db.Discovery, engine string, serialNumber string, reason error) {
+	err := s.discoveryRepo.AddDiscoveryFailure(&db.DiscoveryFailure{
+		DiscoveryId:  discovery.Id,
+		Engine:       engine,
+		SerialNumber: serialNumber,
+		Reason:       reason.Error(),
+	})
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to store discovery failure", zap.String("engine", engine), zap.String("serial_number", serialNumber), zap.Error(err))
+	}
+}
+
+// getInterruptedStatus returns the status of the discovery which did not finish, depending on why it was stopped.
+func getInterruptedStatus(ctx context.Context) string {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errDiscoveryCancelled):
+		return "CANCELLED"
+	case errors.Is(cause, errDiscoveryTimeout):
+		return "TIMED_OUT"
+	}
+	return "FAILED"
+}
+
+func (s *DiscoveryAPIService) updateDiscoveryStatus(ctx context.Context, discovery *db.Discovery, status string) {
+	err := s.discoveryRepo.UpdateDiscoveryStatus(discovery, status)
 	if err != nil {
-		discovery.Status = "FAILED"
 		s.log.With(zax.Get(ctx)...).Error(err.Error())
-		err := s.discoveryRepo.UpdateDiscovery(discovery)
-		if err != nil {
-			s.log.With(zax.Get(ctx)...).Error(err.Error())
+	}
+	// discovery completed with warnings found the certificates, only some of them failed, so it is a successful run
+	if (status == "COMPLETED" || status == "WARNING") && discovery.ScheduleId != nil {
+		if err := s.discoveryRepo.RecordScheduleSuccess(*discovery.ScheduleId, discovery); err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Unable to record successful run of schedule", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
 		}
-		return
 	}
-
-	s.log.With(zax.Get(ctx)...).Info("Discovery completed", zap.String("discovery_uuid", discovery.UUID), zap.String("authority_uuid", authority.UUID), zap.Int("total_certificates", len(discovery.Certificates)))
+	if status == "COMPLETED" || status == "WARNING" {
+		s.refreshStatistics(ctx, discovery)
+	}
 }
@@ -0,0 +1,163 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// DiscoveryScheduleAPIController binds http requests to an api service and writes the service results to the http response
+type DiscoveryScheduleAPIController struct {
+	service      DiscoveryScheduleAPIServicer
+	errorHandler model.ErrorHandler
+}
+
+// NewDiscoveryScheduleAPIController creates a default api controller
+func NewDiscoveryScheduleAPIController(s DiscoveryScheduleAPIServicer) model.Router {
+	return &DiscoveryScheduleAPIController{
+		service:      s,
+		errorHandler: model.DefaultErrorHandler,
+	}
+}
+
+// Routes returns all the api routes for the DiscoveryScheduleAPIController
+func (c *DiscoveryScheduleAPIController) Routes() model.Routes {
+	return model.Routes{
+		"CreateSchedule": model.Route{
+			Method:      strings.ToUpper("Post"),
+			Pattern:     "/v1/discoveryProvider/schedules",
+			HandlerFunc: c.CreateSchedule,
+		},
+		"ListSchedules": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/schedules",
+			HandlerFunc: c.ListSchedules,
+		},
+		"GetSchedule": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/schedules/{uuid}",
+			HandlerFunc: c.GetSchedule,
+		},
+		"UpdateSchedule": model.Route{
+			Method:      strings.ToUpper("Put"),
+			Pattern:     "/v1/discoveryProvider/schedules/{uuid}",
+			HandlerFunc: c.UpdateSchedule,
+		},
+		"DeleteSchedule": model.Route{
+			Method:      strings.ToUpper("Delete"),
+			Pattern:     "/v1/discoveryProvider/schedules/{uuid}",
+			HandlerFunc: c.DeleteSchedule,
+		},
+		"ListScheduleRuns": model.Route{
+			Method:      strings.ToUpper("Get"),
+			Pattern:     "/v1/discoveryProvider/schedules/{uuid}/runs",
+			HandlerFunc: c.ListScheduleRuns,
+		},
+	}
+}
+
+// CreateSchedule - Create schedule of recurring Discovery
+func (c *DiscoveryScheduleAPIController) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	requestParam, ok := c.readScheduleRequest(w, r)
+	if !ok {
+		return
+	}
+	result, err := c.service.CreateSchedule(r.Context(), requestParam)
+	c.writeResult(w, r, result, err)
+}
+
+// ListSchedules - List schedules of recurring Discoveries
+func (c *DiscoveryScheduleAPIController) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.ListSchedules(r.Context())
+	c.writeResult(w, r, result, err)
+}
+
+// GetSchedule - Get schedule with its last run
+func (c *DiscoveryScheduleAPIController) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	uuidParam := mux.Vars(r)["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	result, err := c.service.GetSchedule(r.Context(), uuidParam)
+	c.writeResult(w, r, result, err)
+}
+
+// UpdateSchedule - Update schedule of recurring Discovery
+func (c *DiscoveryScheduleAPIController) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	uuidParam := mux.Vars(r)["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	requestParam, ok := c.readScheduleRequest(w, r)
+	if !ok {
+		return
+	}
+	result, err := c.service.UpdateSchedule(r.Context(), uuidParam, requestParam)
+	c.writeResult(w, r, result, err)
+}
+
+// DeleteSchedule - Delete schedule, the Discoveries started by it are kept
+func (c *DiscoveryScheduleAPIController) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	uuidParam := mux.Vars(r)["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	result, err := c.service.DeleteSchedule(r.Context(), uuidParam)
+	c.writeResult(w, r, result, err)
+}
+
+// ListScheduleRuns - List Discoveries started by the schedule
+func (c *DiscoveryScheduleAPIController) ListScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	uuidParam := mux.Vars(r)["uuid"]
+	if uuidParam == "" {
+		c.errorHandler(w, r, &model.RequiredError{Field: "uuid"}, nil)
+		return
+	}
+	limitParam := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
+			return
+		}
+		limitParam = limit
+	}
+	result, err := c.service.ListScheduleRuns(r.Context(), uuidParam, limitParam)
+	c.writeResult(w, r, result, err)
+}
+
+func (c *DiscoveryScheduleAPIController) readScheduleRequest(w http.ResponseWriter, r *http.Request) (model.DiscoveryScheduleRequestDto, bool) {
+	requestParam := model.DiscoveryScheduleRequestDto{}
+	jsonContent, err := io.ReadAll(r.Body)
+	if err != nil {
+		c.errorHandler(w, r, &model.ParsingError{Err: err}, nil)
+		return requestParam, false
+	}
+	requestParam.Unmarshal(jsonContent)
+	if err := model.AssertDiscoveryScheduleRequestDtoRequired(requestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return requestParam, false
+	}
+	if err := model.AssertDiscoveryScheduleRequestDtoConstraints(requestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return requestParam, false
+	}
+	return requestParam, true
+}
+
+func (c *DiscoveryScheduleAPIController) writeResult(w http.ResponseWriter, r *http.Request, result model.ImplResponse, err error) {
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = model.EncodeJSONResponse(result.Body, &result.Code, w)
+}
@@ -0,0 +1,194 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// CreateSchedule - Create schedule of recurring Discovery
+func (s *DiscoveryAPIService) CreateSchedule(ctx context.Context, request model.DiscoveryScheduleRequestDto) (model.ImplResponse, error) {
+	cron, errs := s.validateSchedule(request)
+	if len(errs) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, errs), nil
+	}
+	if _, err := s.discoveryRepo.FindScheduleByName(request.Name); err == nil {
+		return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Schedule " + request.Name + " already exists."}), nil
+	}
+	schedule := &db.DiscoverySchedule{UUID: utils.GenerateRandomUUID()}
+	applyScheduleRequest(schedule, request, cron)
+	if err := s.discoveryRepo.CreateSchedule(schedule); err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to create schedule", zap.String("schedule_name", request.Name), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to create schedule " + request.Name}), nil
+	}
+	s.log.With(zax.Get(ctx)...).Info("Discovery schedule created", zap.String("schedule_uuid", schedule.UUID), zap.String("cron", schedule.Cron))
+	return model.Response(http.StatusCreated, s.toScheduleDto(ctx, schedule)), nil
+}
+
+// ListSchedules - List schedules of recurring Discoveries
+func (s *DiscoveryAPIService) ListSchedules(ctx context.Context) (model.ImplResponse, error) {
+	schedules, err := s.discoveryRepo.ListSchedules()
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to list schedules", zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to list schedules"}), nil
+	}
+	dtos := make([]model.DiscoveryScheduleDto, 0, len(schedules))
+	for i := range schedules {
+		dtos = append(dtos, s.toScheduleDto(ctx, &schedules[i]))
+	}
+	return model.Response(http.StatusOK, dtos), nil
+}
+
+// GetSchedule - Get schedule with its last run
+func (s *DiscoveryAPIService) GetSchedule(ctx context.Context, uuid string) (model.ImplResponse, error) {
+	schedule, err := s.discoveryRepo.FindScheduleByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Schedule " + uuid + " not found."}), nil
+	}
+	return model.Response(http.StatusOK, s.toScheduleDto(ctx, schedule)), nil
+}
+
+// UpdateSchedule - Update schedule of recurring Discovery
+func (s *DiscoveryAPIService) UpdateSchedule(ctx context.Context, uuid string, request model.DiscoveryScheduleRequestDto) (model.ImplResponse, error) {
+	schedule, err := s.discoveryRepo.FindScheduleByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Schedule " + uuid + " not found."}), nil
+	}
+	cron, errs := s.validateSchedule(request)
+	if len(errs) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, errs), nil
+	}
+	if existing, err := s.discoveryRepo.FindScheduleByName(request.Name); err == nil && existing.Id != schedule.Id {
+		return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Schedule " + request.Name + " already exists."}), nil
+	}
+	applyScheduleRequest(schedule, request, cron)
+	schedule.UpdatedAt = time.Now()
+	if err := s.discoveryRepo.UpdateScheduleDefinition(schedule); err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to update schedule", zap.String("schedule_uuid", schedule.UUID), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to update schedule " + schedule.UUID}), nil
+	}
+	s.log.With(zax.Get(ctx)...).Info("Discovery schedule updated", zap.String("schedule_uuid", schedule.UUID), zap.String("cron", schedule.Cron), zap.Bool("enabled", schedule.Enabled))
+	return model.Response(http.StatusOK, s.toScheduleDto(ctx, schedule)), nil
+}
+
+// DeleteSchedule - Delete schedule, the Discoveries started by it are kept
+func (s *DiscoveryAPIService) DeleteSchedule(ctx context.Context, uuid string) (model.ImplResponse, error) {
+	schedule, err := s.discoveryRepo.FindScheduleByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Schedule " + uuid + " not found."}), nil
+	}
+	if err := s.discoveryRepo.DeleteSchedule(schedule); err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to delete schedule", zap.String("schedule_uuid", schedule.UUID), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to delete schedule " + schedule.UUID}), nil
+	}
+	s.log.With(zax.Get(ctx)...).Info("Discovery schedule deleted", zap.String("schedule_uuid", schedule.UUID))
+	return model.Response(http.StatusNoContent, nil), nil
+}
+
+// ListScheduleRuns - List Discoveries started by the schedule
+func (s *DiscoveryAPIService) ListScheduleRuns(ctx context.Context, uuid string, limit int) (model.ImplResponse, error) {
+	limit, errs := getRunsLimit(limit)
+	if len(errs) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, errs), nil
+	}
+	schedule, err := s.discoveryRepo.FindScheduleByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Schedule " + uuid + " not found."}), nil
+	}
+	// one more run is listed to compare the oldest returned run with it
+	discoveries, total, err := s.discoveryRepo.ListScheduleRuns(schedule, limit+1)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to list runs of schedule", zap.String("schedule_uuid", schedule.UUID), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to list runs of schedule " + schedule.UUID}), nil
+	}
+	runs, err := s.toRunDtos(discoveries, limit)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to count certificates of schedule runs", zap.String("schedule_uuid", schedule.UUID), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to list runs of schedule " + schedule.UUID}), nil
+	}
+	return model.Response(http.StatusOK, model.DiscoveryRunsDto{Total: total, Runs: runs}), nil
+}
+
+// validateSchedule checks the cron expression and the attributes of the discoveries started by the schedule.
+func (s *DiscoveryAPIService) validateSchedule(request model.DiscoveryScheduleRequestDto) (*utils.Cron, []string) {
+	var errs []string
+	cron, err := utils.ParseCron(request.Cron)
+	if err != nil {
+		errs = append(errs, err.Error())
+	} else if cron.Next(time.Now().UTC()).IsZero() {
+		errs = append(errs, "Cron expression "+request.Cron+" never matches")
+	}
+	authorityUuid := getAuthorityUuid(request.Attributes)
+	if authorityUuid == "" {
+		errs = append(errs, "Authority is required")
+	} else if _, err := s.authorityRepo.FindAuthorityInstanceByUUID(authorityUuid); err != nil {
+		errs = append(errs, "Authority not found "+authorityUuid)
+	}
+	if _, err := newCertificateFilter(request.Attributes); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if _, err := newKvOptions(request.Attributes); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return cron, errs
+}
+
+// applyScheduleRequest sets the definition of the schedule and its next run.
+func applyScheduleRequest(schedule *db.DiscoverySchedule, request model.DiscoveryScheduleRequestDto, cron *utils.Cron) {
+	schedule.Name = request.Name
+	schedule.Cron = request.Cron
+	schedule.Kind = request.Kind
+	schedule.Attributes = string(request.RawAttributes)
+	schedule.Enabled = request.Enabled == nil || *request.Enabled
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		next := cron.Next(time.Now().UTC())
+		schedule.NextRunAt = &next
+	}
+}
+
+func (s *DiscoveryAPIService) toScheduleDto(ctx context.Context, schedule *db.DiscoverySchedule) model.DiscoveryScheduleDto {
+	dto := model.DiscoveryScheduleDto{
+		Uuid:                     schedule.UUID,
+		Name:                     schedule.Name,
+		Cron:                     schedule.Cron,
+		Kind:                     schedule.Kind,
+		Attributes:               []byte(schedule.Attributes),
+		Enabled:                  schedule.Enabled,
+		NextRunAt:                schedule.NextRunAt,
+		LastRunAt:                schedule.LastRunAt,
+		LastSuccessAt:            schedule.LastSuccessAt,
+		LastSuccessDiscoveryUuid: schedule.LastSuccessDiscovery,
+	}
+	if len(dto.Attributes) == 0 {
+		dto.Attributes = []byte("[]")
+	}
+	discoveries, _, err := s.discoveryRepo.ListScheduleRuns(schedule, 2)
+	var runs []model.DiscoveryRunDto
+	if err == nil {
+		runs, err = s.toRunDtos(discoveries, 1)
+	}
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Warn("Unable to get last run of schedule", zap.String("schedule_uuid", schedule.UUID), zap.Error(err))
+	} else if len(runs) > 0 {
+		dto.LastRun = &runs[0]
+	}
+	return dto
+}
+
+// getAuthorityUuid returns the UUID of the authority selected by the discovery attributes, or empty string.
+func getAuthorityUuid(attributes []model.Attribute) string {
+	attribute := model.GetAttributeFromArrayByUUID(model.DISCOVERY_AUTHORITY_ATTR, attributes)
+	if attribute == nil || len(attribute.GetContent()) == 0 {
+		return ""
+	}
+	data, _ := attribute.GetContent()[0].GetData().(map[string]interface{})
+	uuid, _ := data["uuid"].(string)
+	return uuid
+}
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	vault2 "github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+)
+
+// discoveredCertificate is the certificate read from the PKI engine, parsed to be matched by the discovery filter
+type discoveredCertificate struct {
+	serialNumber string
+	pem          string
+	certificate  *x509.Certificate
+	meta         certificateMeta
+}
+
+// newDiscoveredCertificate parses the certificate read from the PKI engine and creates its metadata.
+func newDiscoveredCertificate(engine string, serialNumber string, data schema.PkiReadCertResponse, revoked bool) (*discoveredCertificate, error) {
+	block, _ := pem.Decode([]byte(data.Certificate))
+	if block == nil {
+		return nil, errors.New("failed to decode PEM certificate")
+	}
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+
+	meta := newCertificateMeta(engine, parsed)
+	meta.IssuerId = data.IssuerId
+	meta.Revoked = revoked || data.RevocationTime > 0
+	if data.RevocationTime > 0 {
+		revocationTime := time.Unix(data.RevocationTime, 0).UTC()
+		meta.RevocationTime = &revocationTime
+	}
+
+	return &discoveredCertificate{
+		serialNumber: serialNumber,
+		pem:          data.Certificate,
+		certificate:  parsed,
+		meta:         meta,
+	}, nil
+}
+
+// fingerprint returns the SHA-256 fingerprint of the certificate.
+func (c *discoveredCertificate) fingerprint() string {
+	hash := sha256.Sum256(c.certificate.Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// certificateUuid returns the UUID of the certificate, stable across discoveries. The certificate of the PKI engine
+// is identified by the authority, engine, issuer and serial number, the certificate stored in the secret by the authority,
+// engine and its fingerprint, because the same serial number can be issued by different engines and Vault clusters.
+func (c *discoveredCertificate) certificateUuid(authorityUuid string) string {
+	return getCertificateUuid(authorityUuid, c.meta, c.serialNumber, c.fingerprint())
+}
+
+func getCertificateUuid(authorityUuid string, meta certificateMeta, serialNumber string, fingerprint string) string {
+	if meta.SecretPath != "" {
+		return utils.DeterministicGUID(authorityUuid, meta.Engine, fingerprint)
+	}
+	return utils.DeterministicGUID(authorityUuid, meta.Engine, meta.IssuerId, serialNumber)
+}
+
+// toDbCertificate creates the certificate of the authority stored by the discovery.
+func (c *discoveredCertificate) toDbCertificate(authorityUuid string) (*db.Certificate, error) {
+	metaJson, err := json.Marshal(c.meta)
+	if err != nil {
+		return nil, err
+	}
+	certificate := &db.Certificate{
+		SerialNumber:  c.serialNumber,
+		UUID:          c.certificateUuid(authorityUuid),
+		AuthorityUuid: authorityUuid,
+		Engine:        c.meta.Engine,
+		IssuerId:      c.meta.IssuerId,
+		Fingerprint:   c.fingerprint(),
+		NotAfter:      c.meta.NotAfter,
+		Subject:       c.meta.Subject,
+		Revoked:       c.meta.Revoked,
+		Base64Content: base64.StdEncoding.EncodeToString([]byte(c.pem)),
+		Meta:          metaJson,
+	}
+	setParsedColumns(certificate, c.certificate)
+	return certificate, nil
+}
+
+// setParsedColumns sets the columns of the stored certificate parsed from its content, used to search the certificates.
+func setParsedColumns(certificate *db.Certificate, parsed *x509.Certificate) {
+	notBefore := parsed.NotBefore
+	notAfter := parsed.NotAfter
+	hash := sha256.Sum256(parsed.Raw)
+	certificate.Fingerprint = hex.EncodeToString(hash[:])
+	certificate.CommonName = parsed.Subject.CommonName
+	certificate.Sans = getSubjectAlternativeNames(parsed)
+	certificate.IssuerDn = parsed.Issuer.String()
+	certificate.NotBefore = &notBefore
+	certificate.NotAfter = &notAfter
+	certificate.KeyAlgorithm, certificate.KeySize = utils.PublicKeyInfo(parsed)
+	certificate.SignatureAlgorithm = parsed.SignatureAlgorithm.String()
+}
+
+// getSubjectAlternativeNames returns the lower case DNS names, email addresses, IP addresses and URIs of the certificate.
+func getSubjectAlternativeNames(certificate *x509.Certificate) []string {
+	var names []string
+	for _, name := range certificate.DNSNames {
+		names = append(names, strings.ToLower(name))
+	}
+	for _, email := range certificate.EmailAddresses {
+		names = append(names, strings.ToLower(email))
+	}
+	for _, ip := range certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range certificate.URIs {
+		names = append(names, strings.ToLower(uri.String()))
+	}
+	return names
+}
+
+// listRevokedCertificates returns the serial numbers of the certificates revoked by the PKI engine.
+func (s *DiscoveryAPIService) listRevokedCertificates(ctx context.Context, client *vault2.Client, authorityUuid string, engine string) (map[string]bool, error) {
+	release, err := s.runner.Acquire(ctx, authorityUuid)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Secrets.PkiListRevokedCerts(ctx, vault2.WithMountPath(engine))
+	release()
+	revoked := make(map[string]bool)
+	if err != nil {
+		// Vault responds with not found when there are no revoked certificates
+		if vault2.IsErrorStatus(err, http.StatusNotFound) {
+			return revoked, nil
+		}
+		return nil, err
+	}
+	for _, serialNumber := range response.Data.Keys {
+		revoked[serialNumber] = true
+	}
+	return revoked, nil
+}
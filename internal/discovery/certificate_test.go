@@ -0,0 +1,90 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGetSubjectAlternativeNames(t *testing.T) {
+	uri, _ := url.Parse("SPIFFE://Example.com/Service")
+	tests := []struct {
+		name     string
+		template x509.Certificate
+		expected []string
+	}{
+		{name: "none", expected: nil},
+		{name: "DNS names", template: x509.Certificate{DNSNames: []string{"WWW.Example.com", "api.example.com"}}, expected: []string{"www.example.com", "api.example.com"}},
+		{name: "email addresses", template: x509.Certificate{EmailAddresses: []string{"Admin@Example.com"}}, expected: []string{"admin@example.com"}},
+		{name: "IP addresses", template: x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("2001:DB8::1")}}, expected: []string{"10.0.0.1", "2001:db8::1"}},
+		{name: "URIs", template: x509.Certificate{URIs: []*url.URL{uri}}, expected: []string{"spiffe://example.com/service"}},
+		{
+			name: "all kinds ordered",
+			template: x509.Certificate{
+				DNSNames:       []string{"example.com"},
+				EmailAddresses: []string{"admin@example.com"},
+				IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+				URIs:           []*url.URL{uri},
+			},
+			expected: []string{"example.com", "admin@example.com", "10.0.0.1", "spiffe://example.com/service"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template := test.template
+			template.SerialNumber = big.NewInt(1)
+			template.Subject = pkix.Name{CommonName: "example.com"}
+			template.NotBefore = time.Now().Add(-time.Hour)
+			template.NotAfter = time.Now().Add(time.Hour)
+			names := getSubjectAlternativeNames(createCertificateFromTemplate(t, &template))
+			if len(names) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, names)
+			}
+			for i := range test.expected {
+				if names[i] != test.expected[i] {
+					t.Fatalf("expected %v, got %v", test.expected, names)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStoredColumns(t *testing.T) {
+	notAfter := time.Now().AddDate(0, 0, 30).UTC().Truncate(time.Second)
+	parsed := createCertificate(t, "example.com", []string{"www.example.com"}, notAfter)
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: parsed.Raw})
+	hash := sha256.Sum256(parsed.Raw)
+
+	certificate := &db.Certificate{KeyAlgorithm: "unknown", Base64Content: base64.StdEncoding.EncodeToString(content)}
+	parseStoredColumns(certificate)
+	if certificate.Fingerprint != hex.EncodeToString(hash[:]) {
+		t.Fatalf("unexpected fingerprint %q", certificate.Fingerprint)
+	}
+	if certificate.CommonName != "example.com" || certificate.IssuerDn != "CN=example.com" ||
+		len(certificate.Sans) != 1 || certificate.Sans[0] != "www.example.com" {
+		t.Fatalf("unexpected names %q, %q, %v", certificate.CommonName, certificate.IssuerDn, certificate.Sans)
+	}
+	if certificate.NotAfter == nil || !certificate.NotAfter.Equal(notAfter) || certificate.NotBefore == nil {
+		t.Fatalf("unexpected validity %v - %v", certificate.NotBefore, certificate.NotAfter)
+	}
+	if certificate.KeyAlgorithm == "" || certificate.KeyAlgorithm == "unknown" || certificate.KeySize != 256 || certificate.SignatureAlgorithm != x509.ECDSAWithSHA256.String() {
+		t.Fatalf("unexpected key %q %d signed with %q", certificate.KeyAlgorithm, certificate.KeySize, certificate.SignatureAlgorithm)
+	}
+
+	for _, value := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("not PEM"))} {
+		invalid := &db.Certificate{KeyAlgorithm: "unknown", Base64Content: value}
+		parseStoredColumns(invalid)
+		if invalid.KeyAlgorithm != "" || invalid.Fingerprint != "" {
+			t.Fatalf("expected empty columns for %q, got %+v", value, invalid)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// defaultDiffItemsPerPage is the number of certificates per page when not specified
+const defaultDiffItemsPerPage = 100
+
+// GetDiscoveryDiff - Compare the Discovery with the previous one
+func (s *DiscoveryAPIService) GetDiscoveryDiff(ctx context.Context, uuid string, request model.DiscoveryDiffRequestDto) (model.ImplResponse, error) {
+	if errs := validateDiffRequest(&request); len(errs) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, errs), nil
+	}
+	discovery, err := s.discoveryRepo.FindDiscoveryByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + uuid + " not found."}), nil
+	}
+	var base *db.Discovery
+	if request.BaseUuid != "" {
+		base, err = s.discoveryRepo.FindDiscoveryByUUID(request.BaseUuid)
+		if err != nil {
+			return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + request.BaseUuid + " not found."}), nil
+		}
+	} else {
+		base, err = s.discoveryRepo.FindPreviousDiscovery(discovery)
+		if err != nil {
+			return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "No previous completed discovery of the authority of discovery " + uuid + " found."}), nil
+		}
+	}
+
+	counts, err := s.discoveryRepo.CountDiff(base, discovery)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to count changes of discovery", zap.String("discovery_uuid", discovery.UUID), zap.String("base_discovery_uuid", base.UUID), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to compare discovery " + discovery.UUID}), nil
+	}
+	response := model.DiscoveryDiffDto{
+		BaseUuid: base.UUID,
+		Uuid:     discovery.UUID,
+		Added:    counts[db.DIFF_ADDED],
+		Removed:  counts[db.DIFF_REMOVED],
+		Changed:  counts[db.DIFF_CHANGED],
+	}
+	offset, limit := 0, 0
+	if !request.Export {
+		var total int64
+		for _, change := range request.Changes {
+			total += counts[change]
+		}
+		if len(request.Changes) == 0 {
+			total = response.Added + response.Removed + response.Changed
+		}
+		response.PageNumber = request.PageNumber
+		response.ItemsPerPage = request.ItemsPerPage
+		response.TotalPages = (total + request.ItemsPerPage - 1) / request.ItemsPerPage
+		offset, limit = int((request.PageNumber-1)*request.ItemsPerPage), int(request.ItemsPerPage)
+	}
+	certificates, err := s.discoveryRepo.ListDiff(base, discovery, request.Changes, offset, limit)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to list changes of discovery", zap.String("discovery_uuid", discovery.UUID), zap.String("base_discovery_uuid", base.UUID), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to compare discovery " + discovery.UUID}), nil
+	}
+	response.Certificates = make([]model.DiscoveryDiffCertificateDto, 0, len(certificates))
+	for _, certificate := range certificates {
+		response.Certificates = append(response.Certificates, model.DiscoveryDiffCertificateDto{
+			Change:        certificate.Change,
+			Uuid:          certificate.UUID,
+			SerialNumber:  certificate.SerialNumber,
+			Subject:       certificate.Subject,
+			NotAfter:      certificate.NotAfter,
+			Engine:        certificate.Engine,
+			RevokedBefore: certificate.RevokedBefore,
+			Revoked:       certificate.Revoked,
+		})
+	}
+	return model.Response(http.StatusOK, response), nil
+}
+
+// validateDiffRequest sets the default page and returns the reasons why the request is invalid.
+func validateDiffRequest(request *model.DiscoveryDiffRequestDto) []string {
+	var errs []string
+	for _, change := range request.Changes {
+		if change != db.DIFF_ADDED && change != db.DIFF_REMOVED && change != db.DIFF_CHANGED {
+			errs = append(errs, "Unsupported change "+change+", supported are "+db.DIFF_ADDED+", "+db.DIFF_REMOVED+" and "+db.DIFF_CHANGED)
+		}
+	}
+	if request.Export {
+		return errs
+	}
+	if request.PageNumber == 0 {
+		request.PageNumber = 1
+	}
+	if request.ItemsPerPage == 0 {
+		request.ItemsPerPage = defaultDiffItemsPerPage
+	}
+	if request.PageNumber < 1 {
+		errs = append(errs, "Page number must be greater than 0")
+	}
+	if request.ItemsPerPage < 1 || request.ItemsPerPage > maxItemsPerPage {
+		errs = append(errs, "Items per page must be between 1 and "+strconv.Itoa(maxItemsPerPage))
+	}
+	return errs
+}
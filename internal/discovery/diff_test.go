@@ -0,0 +1,38 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"testing"
+)
+
+func TestValidateDiffRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		request      model.DiscoveryDiffRequestDto
+		errors       int
+		pageNumber   int64
+		itemsPerPage int64
+	}{
+		{name: "defaults", request: model.DiscoveryDiffRequestDto{}, pageNumber: 1, itemsPerPage: defaultDiffItemsPerPage},
+		{name: "page", request: model.DiscoveryDiffRequestDto{PageNumber: 3, ItemsPerPage: 20}, pageNumber: 3, itemsPerPage: 20},
+		{name: "changes", request: model.DiscoveryDiffRequestDto{Changes: []string{db.DIFF_ADDED, db.DIFF_REMOVED, db.DIFF_CHANGED}}, pageNumber: 1, itemsPerPage: defaultDiffItemsPerPage},
+		{name: "unsupported change", request: model.DiscoveryDiffRequestDto{Changes: []string{db.DIFF_ADDED, "RENAMED"}}, errors: 1, pageNumber: 1, itemsPerPage: defaultDiffItemsPerPage},
+		{name: "negative page number", request: model.DiscoveryDiffRequestDto{PageNumber: -1}, errors: 1, pageNumber: -1, itemsPerPage: defaultDiffItemsPerPage},
+		{name: "too many items per page", request: model.DiscoveryDiffRequestDto{ItemsPerPage: maxItemsPerPage + 1}, errors: 1, pageNumber: 1, itemsPerPage: maxItemsPerPage + 1},
+		{name: "export ignores page", request: model.DiscoveryDiffRequestDto{Export: true, PageNumber: -1, ItemsPerPage: -1}, pageNumber: -1, itemsPerPage: -1},
+		{name: "export with unsupported change", request: model.DiscoveryDiffRequestDto{Export: true, Changes: []string{"RENAMED"}}, errors: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := test.request
+			errors := validateDiffRequest(&request)
+			if len(errors) != test.errors {
+				t.Fatalf("expected %d errors, got %v", test.errors, errors)
+			}
+			if request.PageNumber != test.pageNumber || request.ItemsPerPage != test.itemsPerPage {
+				t.Fatalf("expected page %d with %d items, got page %d with %d items", test.pageNumber, test.itemsPerPage, request.PageNumber, request.ItemsPerPage)
+			}
+		})
+	}
+}
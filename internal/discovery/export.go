@@ -0,0 +1,158 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// Formats of the exported discovery results
+const (
+	EXPORT_PEM   = "pem"
+	EXPORT_CSV   = "csv"
+	EXPORT_JSONL = "jsonl"
+)
+
+var exportContentTypes = map[string]string{
+	EXPORT_PEM:   "application/x-pem-file",
+	EXPORT_CSV:   "text/csv; charset=UTF-8",
+	EXPORT_JSONL: "application/jsonl; charset=UTF-8",
+}
+
+var exportCsvHeader = []string{"uuid", "serialNumber", "subject", "issuer", "notAfter", "engine", "revoked"}
+
+// exportedCertificate is the certificate of the discovery written to the CSV and JSON Lines export
+type exportedCertificate struct {
+	Uuid          string     `json:"uuid"`
+	SerialNumber  string     `json:"serialNumber"`
+	Subject       string     `json:"subject"`
+	Issuer        string     `json:"issuer"`
+	NotAfter      *time.Time `json:"notAfter,omitempty"`
+	Engine        string     `json:"engine"`
+	Revoked       bool       `json:"revoked"`
+	Base64Content string     `json:"base64Content"`
+}
+
+// ExportDiscovery - Export all certificates of the Discovery
+func (s *DiscoveryAPIService) ExportDiscovery(ctx context.Context, uuid string, format string) (model.ImplResponse, error) {
+	format = strings.ToLower(format)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return model.Response(http.StatusUnprocessableEntity, []string{"Unsupported export format " + format + ", supported are " + EXPORT_PEM + ", " + EXPORT_CSV + " and " + EXPORT_JSONL}), nil
+	}
+	discovery, err := s.discoveryRepo.FindDiscoveryByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + uuid + " not found."}), nil
+	}
+	if discovery.Status == "IN_PROGRESS" {
+		return model.Response(http.StatusConflict, model.ErrorMessageDto{Message: "Discovery " + uuid + " is still running."}), nil
+	}
+
+	return model.Response(http.StatusOK, &model.StreamedBody{
+		ContentType: contentType,
+		FileName:    "discovery-" + discovery.UUID + "." + format,
+		Write: func(w io.Writer) error {
+			err := writeExport(w, func(f func(certificate *db.Certificate) error) error {
+				return s.discoveryRepo.StreamDiscoveryCertificates(discovery, f)
+			}, format)
+			if err != nil {
+				// the response is already started, the export ends incomplete
+				s.log.With(zax.Get(ctx)...).Error("Unable to export discovery", zap.String("discovery_uuid", discovery.UUID), zap.String("format", format), zap.Error(err))
+			}
+			return err
+		},
+	}), nil
+}
+
+// certificateStream calls the function for each certificate of the exported discovery until it returns an error.
+type certificateStream func(f func(certificate *db.Certificate) error) error
+
+// writeExport writes the certificates of the stream to the writer in the format.
+func writeExport(w io.Writer, stream certificateStream, format string) error {
+	if format == EXPORT_CSV {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportCsvHeader); err != nil {
+			return err
+		}
+		err := stream(func(certificate *db.Certificate) error {
+			exported := newExportedCertificate(certificate)
+			notAfter := ""
+			if exported.NotAfter != nil {
+				notAfter = exported.NotAfter.UTC().Format(time.RFC3339)
+			}
+			return writer.Write([]string{exported.Uuid, exported.SerialNumber, exported.Subject, exported.Issuer, notAfter, exported.Engine, strconv.FormatBool(exported.Revoked)})
+		})
+		writer.Flush()
+		if err != nil {
+			return err
+		}
+		return writer.Error()
+	}
+
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	err := stream(func(certificate *db.Certificate) error {
+		if format == EXPORT_JSONL {
+			return encoder.Encode(newExportedCertificate(certificate))
+		}
+		content, err := base64.StdEncoding.DecodeString(certificate.Base64Content)
+		if err != nil {
+			return err
+		}
+		if _, err := buffered.Write(content); err != nil {
+			return err
+		}
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			return buffered.WriteByte('\n')
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// newExportedCertificate returns the stored certificate with the issuer parsed from its content.
+func newExportedCertificate(certificate *db.Certificate) exportedCertificate {
+	exported := exportedCertificate{
+		Uuid:          certificate.UUID,
+		SerialNumber:  certificate.SerialNumber,
+		Subject:       certificate.Subject,
+		NotAfter:      certificate.NotAfter,
+		Engine:        certificate.Engine,
+		Revoked:       certificate.Revoked,
+		Base64Content: certificate.Base64Content,
+	}
+	content, err := base64.StdEncoding.DecodeString(certificate.Base64Content)
+	if err != nil {
+		return exported
+	}
+	if block, _ := pem.Decode(content); block != nil {
+		if parsed, err := x509.ParseCertificate(block.Bytes); err == nil {
+			exported.Issuer = parsed.Issuer.String()
+			if exported.Subject == "" {
+				exported.Subject = parsed.Subject.String()
+			}
+			if exported.NotAfter == nil {
+				notAfter := parsed.NotAfter
+				exported.NotAfter = &notAfter
+			}
+		}
+	}
+	return exported
+}
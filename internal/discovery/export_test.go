@@ -0,0 +1,131 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportedCertificates(t *testing.T) []*db.Certificate {
+	notAfter := time.Now().AddDate(0, 0, 30).UTC().Truncate(time.Second)
+	var certificates []*db.Certificate
+	for i, name := range []string{"first.example.com", "second.example.com"} {
+		certificate := createCertificate(t, name, nil, notAfter)
+		content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+		certificates = append(certificates, &db.Certificate{
+			UUID:          "uuid-" + name,
+			SerialNumber:  "01",
+			Engine:        "pki",
+			Revoked:       i == 1,
+			Base64Content: base64.StdEncoding.EncodeToString(content),
+		})
+	}
+	return certificates
+}
+
+func streamOf(certificates []*db.Certificate, err error) certificateStream {
+	return func(f func(certificate *db.Certificate) error) error {
+		for _, certificate := range certificates {
+			if err := f(certificate); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+func TestWriteExportCsv(t *testing.T) {
+	certificates := exportedCertificates(t)
+	var buffer bytes.Buffer
+	if err := writeExport(&buffer, streamOf(certificates, nil), EXPORT_CSV); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0][0] != exportCsvHeader[0] {
+		t.Fatalf("expected header and 2 records, got %v", records)
+	}
+	for i, certificate := range certificates {
+		record := records[i+1]
+		subject := "CN=" + strings.TrimPrefix(certificate.UUID, "uuid-")
+		if record[0] != certificate.UUID || record[2] != subject || record[3] != subject || record[6] != strconv.FormatBool(certificate.Revoked) {
+			t.Fatalf("unexpected record %v", record)
+		}
+		if _, err := time.Parse(time.RFC3339, record[4]); err != nil {
+			t.Fatalf("unexpected not after %q: %v", record[4], err)
+		}
+	}
+}
+
+func TestWriteExportJsonl(t *testing.T) {
+	certificates := exportedCertificates(t)
+	var buffer bytes.Buffer
+	if err := writeExport(&buffer, streamOf(certificates, nil), EXPORT_JSONL); err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(&buffer)
+	var exported []exportedCertificate
+	for scanner.Scan() {
+		var certificate exportedCertificate
+		if err := json.Unmarshal(scanner.Bytes(), &certificate); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		exported = append(exported, certificate)
+	}
+	if len(exported) != len(certificates) {
+		t.Fatalf("expected %d lines, got %d", len(certificates), len(exported))
+	}
+	for i, certificate := range certificates {
+		if exported[i].Uuid != certificate.UUID || exported[i].Base64Content != certificate.Base64Content ||
+			exported[i].Revoked != certificate.Revoked || exported[i].NotAfter == nil || exported[i].Issuer == "" {
+			t.Fatalf("unexpected certificate %+v", exported[i])
+		}
+	}
+}
+
+func TestWriteExportPem(t *testing.T) {
+	certificates := exportedCertificates(t)
+	var buffer bytes.Buffer
+	if err := writeExport(&buffer, streamOf(certificates, nil), EXPORT_PEM); err != nil {
+		t.Fatal(err)
+	}
+	rest := buffer.Bytes()
+	for i, certificate := range certificates {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		expected, _ := base64.StdEncoding.DecodeString(certificate.Base64Content)
+		if block == nil || !bytes.Equal(pem.EncodeToMemory(block), expected) {
+			t.Fatalf("unexpected PEM block %d", i)
+		}
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		t.Fatalf("unexpected content after certificates %q", rest)
+	}
+}
+
+func TestWriteExportStreamError(t *testing.T) {
+	streamErr := errors.New("connection lost")
+	for _, format := range []string{EXPORT_CSV, EXPORT_JSONL, EXPORT_PEM} {
+		var buffer bytes.Buffer
+		if err := writeExport(&buffer, streamOf(exportedCertificates(t), streamErr), format); !errors.Is(err, streamErr) {
+			t.Fatalf("expected stream error for %s, got %v", format, err)
+		}
+	}
+
+	invalid := []*db.Certificate{{UUID: "invalid", Base64Content: "not base64!"}}
+	var buffer bytes.Buffer
+	if err := writeExport(&buffer, streamOf(invalid, nil), EXPORT_PEM); err == nil {
+		t.Fatal("expected error for invalid certificate content")
+	}
+}
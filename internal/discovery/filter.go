@@ -0,0 +1,162 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	vault2 "github.com/hashicorp/vault-client-go"
+)
+
+// certificateFilter selects the discovered certificates which are stored, it is recorded in the discovery meta
+type certificateFilter struct {
+	SkipExpired        bool     `json:"skipExpired,omitempty"`
+	SkipRevoked        bool     `json:"skipRevoked,omitempty"`
+	ExpiringWithinDays int      `json:"expiringWithinDays,omitempty"`
+	SubjectPattern     string   `json:"subjectPattern,omitempty"`
+	Issuers            []string `json:"issuers,omitempty"`
+
+	subjectPattern *regexp.Regexp
+}
+
+// newCertificateFilter creates the filter from the discovery attributes.
+func newCertificateFilter(attributes []model.Attribute) (*certificateFilter, error) {
+	filter := &certificateFilter{
+		SkipExpired: getBooleanAttribute(model.DISCOVERY_SKIP_EXPIRED_ATTR, attributes),
+		SkipRevoked: getBooleanAttribute(model.DISCOVERY_SKIP_REVOKED_ATTR, attributes),
+	}
+	if attribute := model.GetAttributeFromArrayByUUID(model.DISCOVERY_EXPIRING_WITHIN_ATTR, attributes); attribute != nil && len(attribute.GetContent()) > 0 {
+		days, _ := attribute.GetContent()[0].GetData().(int32)
+		if days < 0 {
+			return nil, fmt.Errorf("number of days to expiration must not be negative")
+		}
+		filter.ExpiringWithinDays = int(days)
+	}
+	filter.SubjectPattern = strings.TrimSpace(getStringAttribute(model.DISCOVERY_SUBJECT_PATTERN_ATTR, attributes))
+	for _, issuer := range strings.Split(getStringAttribute(model.DISCOVERY_ISSUERS_ATTR, attributes), ",") {
+		if issuer = strings.TrimSpace(issuer); issuer != "" {
+			filter.Issuers = append(filter.Issuers, issuer)
+		}
+	}
+	if err := filter.compile(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// compile prepares the filter for matching, it must be called on the filter loaded from the discovery meta.
+func (f *certificateFilter) compile() error {
+	if f.SubjectPattern == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(f.SubjectPattern)
+	if err != nil {
+		return fmt.Errorf("invalid subject pattern: %v", err)
+	}
+	f.subjectPattern = pattern
+	return nil
+}
+
+func (f *certificateFilter) isEmpty() bool {
+	return !f.SkipExpired && !f.SkipRevoked && f.ExpiringWithinDays == 0 && f.SubjectPattern == "" && len(f.Issuers) == 0
+}
+
+// matches returns true when the certificate passes the filter. The issuers are the IDs of the issuers
+// of the engine selected by the filter.
+func (f *certificateFilter) matches(c *discoveredCertificate, issuers map[string]bool) bool {
+	now := time.Now()
+	if f.SkipExpired && c.certificate.NotAfter.Before(now) {
+		return false
+	}
+	if f.SkipRevoked && c.meta.Revoked {
+		return false
+	}
+	if f.ExpiringWithinDays > 0 && c.certificate.NotAfter.After(now.AddDate(0, 0, f.ExpiringWithinDays)) {
+		return false
+	}
+	if f.subjectPattern != nil && !f.matchesSubject(c) {
+		return false
+	}
+	if len(f.Issuers) > 0 && !issuers[c.meta.IssuerId] {
+		return false
+	}
+	return true
+}
+
+func (f *certificateFilter) matchesSubject(c *discoveredCertificate) bool {
+	names := []string{c.certificate.Subject.CommonName}
+	names = append(names, c.certificate.DNSNames...)
+	names = append(names, c.certificate.EmailAddresses...)
+	for _, ip := range c.certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range c.certificate.URIs {
+		names = append(names, uri.String())
+	}
+	for _, name := range names {
+		if name != "" && f.subjectPattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// toMetadata returns the active filters as metadata attribute, or nil when no filter is active.
+func (f *certificateFilter) toMetadata() *model.MetadataAttribute {
+	if f == nil || f.isEmpty() {
+		return nil
+	}
+	var content []model.AttributeContent
+	if f.SkipExpired {
+		content = append(content, model.StringAttributeContent{Reference: "skipExpired", Data: "Expired certificates skipped"})
+	}
+	if f.SkipRevoked {
+		content = append(content, model.StringAttributeContent{Reference: "skipRevoked", Data: "Revoked certificates skipped"})
+	}
+	if f.ExpiringWithinDays > 0 {
+		content = append(content, model.StringAttributeContent{Reference: "expiringWithinDays", Data: "Expiring within " + strconv.Itoa(f.ExpiringWithinDays) + " days"})
+	}
+	if f.SubjectPattern != "" {
+		content = append(content, model.StringAttributeContent{Reference: "subjectPattern", Data: "Subject matching " + f.SubjectPattern})
+	}
+	if len(f.Issuers) > 0 {
+		content = append(content, model.StringAttributeContent{Reference: "issuers", Data: "Issued by " + strings.Join(f.Issuers, ", ")})
+	}
+	attribute := metadataAttribute(model.DISCOVERY_FILTERS_META_ATTR, "filters", "Filters", model.STRING, content...)
+	return &attribute
+}
+
+// resolveIssuers returns the IDs of the issuers of the engine selected by the filter by their ID or name.
+func (s *DiscoveryAPIService) resolveIssuers(ctx context.Context, client *vault2.Client, authorityUuid string, engine string, filter *certificateFilter) (map[string]bool, error) {
+	issuers := make(map[string]bool)
+	if len(filter.Issuers) == 0 {
+		return issuers, nil
+	}
+	selected := make(map[string]bool)
+	for _, issuer := range filter.Issuers {
+		selected[issuer] = true
+	}
+	release, err := s.runner.Acquire(ctx, authorityUuid)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Secrets.PkiListIssuers(ctx, vault2.WithMountPath(engine))
+	release()
+	if err != nil {
+		return nil, err
+	}
+	for _, issuerId := range response.Data.Keys {
+		issuerName := ""
+		if info, ok := response.Data.KeyInfo[issuerId].(map[string]interface{}); ok {
+			issuerName, _ = info["issuer_name"].(string)
+		}
+		if selected[issuerId] || (issuerName != "" && selected[issuerName]) {
+			issuers[issuerId] = true
+		}
+	}
+	return issuers, nil
+}
@@ -0,0 +1,153 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func createCertificate(t *testing.T, commonName string, dnsNames []string, notAfter time.Time) *x509.Certificate {
+	return createCertificateFromTemplate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	})
+}
+
+// createCertificateFromTemplate creates the self-signed certificate with new P-256 key.
+func createCertificateFromTemplate(t *testing.T, template *x509.Certificate) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func TestNewCertificateFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes []model.Attribute
+		expected   certificateFilter
+		wantErr    bool
+	}{
+		{
+			name:     "empty",
+			expected: certificateFilter{},
+		},
+		{
+			name: "all filters",
+			attributes: []model.Attribute{
+				model.RequestAttributeDto{Uuid: model.DISCOVERY_SKIP_EXPIRED_ATTR, Content: []model.AttributeContent{model.BooleanAttributeContent{Data: true}}},
+				model.RequestAttributeDto{Uuid: model.DISCOVERY_SKIP_REVOKED_ATTR, Content: []model.AttributeContent{model.BooleanAttributeContent{Data: true}}},
+				model.RequestAttributeDto{Uuid: model.DISCOVERY_EXPIRING_WITHIN_ATTR, Content: []model.AttributeContent{model.IntegerAttributeContent{Data: 30}}},
+				model.RequestAttributeDto{Uuid: model.DISCOVERY_SUBJECT_PATTERN_ATTR, Content: []model.AttributeContent{model.StringAttributeContent{Data: " ^www\\. "}}},
+				model.RequestAttributeDto{Uuid: model.DISCOVERY_ISSUERS_ATTR, Content: []model.AttributeContent{model.StringAttributeContent{Data: "root, ,intermediate"}}},
+			},
+			expected: certificateFilter{
+				SkipExpired:        true,
+				SkipRevoked:        true,
+				ExpiringWithinDays: 30,
+				SubjectPattern:     "^www\\.",
+				Issuers:            []string{"root", "intermediate"},
+			},
+		},
+		{
+			name: "negative days to expiration",
+			attributes: []model.Attribute{
+				model.RequestAttributeDto{Uuid: model.DISCOVERY_EXPIRING_WITHIN_ATTR, Content: []model.AttributeContent{model.IntegerAttributeContent{Data: -1}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid subject pattern",
+			attributes: []model.Attribute{
+				model.RequestAttributeDto{Uuid: model.DISCOVERY_SUBJECT_PATTERN_ATTR, Content: []model.AttributeContent{model.StringAttributeContent{Data: "("}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := newCertificateFilter(test.attributes)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got filter %+v", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filter.SkipExpired != test.expected.SkipExpired || filter.SkipRevoked != test.expected.SkipRevoked ||
+				filter.ExpiringWithinDays != test.expected.ExpiringWithinDays || filter.SubjectPattern != test.expected.SubjectPattern ||
+				len(filter.Issuers) != len(test.expected.Issuers) {
+				t.Fatalf("expected filter %+v, got %+v", test.expected, *filter)
+			}
+			for i := range test.expected.Issuers {
+				if filter.Issuers[i] != test.expected.Issuers[i] {
+					t.Fatalf("expected issuers %v, got %v", test.expected.Issuers, filter.Issuers)
+				}
+			}
+			if filter.isEmpty() != test.expected.isEmpty() {
+				t.Fatalf("unexpected isEmpty %v", filter.isEmpty())
+			}
+		})
+	}
+}
+
+func TestCertificateFilterMatches(t *testing.T) {
+	now := time.Now()
+	valid := createCertificate(t, "example.com", []string{"www.example.com"}, now.AddDate(0, 0, 60))
+	expiring := createCertificate(t, "expiring.com", nil, now.AddDate(0, 0, 10))
+	expired := createCertificate(t, "expired.com", nil, now.AddDate(0, 0, -1))
+	issuers := map[string]bool{"issuer-1": true}
+
+	tests := []struct {
+		name        string
+		filter      certificateFilter
+		certificate *x509.Certificate
+		meta        certificateMeta
+		expected    bool
+	}{
+		{name: "no filter", certificate: expired, meta: certificateMeta{Revoked: true}, expected: true},
+		{name: "skip expired", filter: certificateFilter{SkipExpired: true}, certificate: expired, expected: false},
+		{name: "skip expired valid", filter: certificateFilter{SkipExpired: true}, certificate: valid, expected: true},
+		{name: "skip revoked", filter: certificateFilter{SkipRevoked: true}, certificate: valid, meta: certificateMeta{Revoked: true}, expected: false},
+		{name: "skip revoked not revoked", filter: certificateFilter{SkipRevoked: true}, certificate: valid, expected: true},
+		{name: "expiring within", filter: certificateFilter{ExpiringWithinDays: 30}, certificate: expiring, expected: true},
+		{name: "expiring later", filter: certificateFilter{ExpiringWithinDays: 30}, certificate: valid, expected: false},
+		{name: "subject common name", filter: certificateFilter{SubjectPattern: "^example"}, certificate: valid, expected: true},
+		{name: "subject DNS name", filter: certificateFilter{SubjectPattern: "^www\\."}, certificate: valid, expected: true},
+		{name: "subject not matching", filter: certificateFilter{SubjectPattern: "^www\\."}, certificate: expiring, expected: false},
+		{name: "issuer", filter: certificateFilter{Issuers: []string{"root"}}, certificate: valid, meta: certificateMeta{IssuerId: "issuer-1"}, expected: true},
+		{name: "other issuer", filter: certificateFilter{Issuers: []string{"root"}}, certificate: valid, meta: certificateMeta{IssuerId: "issuer-2"}, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := test.filter
+			if err := filter.compile(); err != nil {
+				t.Fatal(err)
+			}
+			c := &discoveredCertificate{certificate: test.certificate, meta: test.meta}
+			if matches := filter.matches(c, issuers); matches != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, matches)
+			}
+		})
+	}
+}
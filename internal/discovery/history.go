@@ -0,0 +1,84 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultRuns is the number of runs returned when the limit is not specified
+	defaultRuns = 20
+	maxRuns     = 1000
+)
+
+// GetDiscoveryHistory - List runs of the Discovery with the same name
+func (s *DiscoveryAPIService) GetDiscoveryHistory(ctx context.Context, uuid string, limit int) (model.ImplResponse, error) {
+	limit, errs := getRunsLimit(limit)
+	if len(errs) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, errs), nil
+	}
+	discovery, err := s.discoveryRepo.FindDiscoveryByUUID(uuid)
+	if err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + uuid + " not found."}), nil
+	}
+	// one more run is listed to compare the oldest returned run with it
+	discoveries, total, err := s.discoveryRepo.ListDiscoveryRuns(discovery.Name, limit+1)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to list runs of discovery", zap.String("discovery_name", discovery.Name), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to list runs of discovery " + discovery.UUID}), nil
+	}
+	runs, err := s.toRunDtos(discoveries, limit)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to count certificates of discovery runs", zap.String("discovery_name", discovery.Name), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to list runs of discovery " + discovery.UUID}), nil
+	}
+	return model.Response(http.StatusOK, model.DiscoveryRunsDto{Total: total, Runs: runs}), nil
+}
+
+// getRunsLimit returns the number of runs to list, or the reasons why the limit is invalid.
+func getRunsLimit(limit int) (int, []string) {
+	if limit == 0 {
+		return defaultRuns, nil
+	}
+	if limit < 1 || limit > maxRuns {
+		return 0, []string{"Limit must be between 1 and " + strconv.Itoa(maxRuns)}
+	}
+	return limit, nil
+}
+
+// toRunDtos returns up to limit runs with the number of certificates discovered by each of them compared to the previous run.
+// The runs are ordered starting with the most recent, the run following the limit is used only for the comparison.
+func (s *DiscoveryAPIService) toRunDtos(discoveries []db.Discovery, limit int) ([]model.DiscoveryRunDto, error) {
+	ids := make([]uint, 0, len(discoveries))
+	for _, discovery := range discoveries {
+		ids = append(ids, discovery.Id)
+	}
+	counts, err := s.discoveryRepo.CountDiscoveryCertificates(ids)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]model.DiscoveryRunDto, 0, min(len(discoveries), limit))
+	for i := 0; i < len(discoveries) && i < limit; i++ {
+		discovery := &discoveries[i]
+		run := model.DiscoveryRunDto{
+			Uuid:                        discovery.UUID,
+			Name:                        discovery.Name,
+			Status:                      getDiscoveryStatus(discovery.Status),
+			CreatedAt:                   discovery.CreatedAt,
+			TotalCertificatesDiscovered: counts[discovery.Id],
+			CertificatesFailed:          int64(discovery.CertificatesFailed),
+		}
+		if i+1 < len(discoveries) {
+			change := counts[discovery.Id] - counts[discoveries[i+1].Id]
+			run.CertificatesChange = &change
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// migrationKey identifies the update of the certificates stored by the previous versions in the runner
+const migrationKey = "certificate-migration"
+
+// identityChunk is the number of certificates listed at once
+const identityChunk = 500
+
+// MigrateStoredCertificates updates the certificates stored by the previous versions in the background. The identity
+// of the certificates is updated first, then their columns are parsed, because parsing sets the fingerprint by which
+// the certificates without the identity are found.
+func (s *DiscoveryAPIService) MigrateStoredCertificates() {
+	s.runner.Go(migrationKey, func(ctx context.Context) {
+		if s.migrateCertificateIdentities(ctx) {
+			s.parseStoredCertificates(ctx)
+		}
+	})
+}
+
+// migrateCertificateIdentities computes the fingerprint and the uuid of the certificates stored before they were
+// derived from the authority, engine, issuer and serial number, so that the certificates keep their identity when
+// they are discovered again. It returns true when the identity of all certificates is updated.
+func (s *DiscoveryAPIService) migrateCertificateIdentities(ctx context.Context) bool {
+	var after uint
+	updated := 0
+	for ctx.Err() == nil {
+		certificates, err := s.discoveryRepo.ListCertificatesWithoutFingerprint(after, identityChunk)
+		if err != nil {
+			s.log.Error("Unable to list certificates without identity", zap.Error(err))
+			return false
+		}
+		for _, certificate := range certificates {
+			after = certificate.Id
+			uuid, fingerprint := getStoredCertificateIdentity(certificate)
+			err := s.discoveryRepo.UpdateCertificateIdentity(certificate, uuid, fingerprint)
+			if errors.Is(err, db.ErrCertificateExists) {
+				// the certificate was stored by the discovery in the meantime, it is updated on the next start
+				s.log.Warn("Unable to update identity of certificate", zap.String("certificate_uuid", certificate.UUID), zap.Error(err))
+				continue
+			}
+			if err != nil {
+				s.log.Error("Unable to update identity of certificate", zap.String("certificate_uuid", certificate.UUID), zap.Error(err))
+				return false
+			}
+			updated++
+		}
+		if len(certificates) < identityChunk {
+			break
+		}
+	}
+	if updated > 0 {
+		s.log.Info("Updated identity of stored certificates", zap.Int("certificates", updated))
+	}
+	return ctx.Err() == nil
+}
+
+// getStoredCertificateIdentity returns the uuid and the fingerprint of the certificate stored before they were added.
+// The uuid is kept when the authority or the engine of the certificate is not known, the fingerprint is empty
+// when the certificate can not be parsed.
+func getStoredCertificateIdentity(certificate *db.Certificate) (string, string) {
+	stored, err := parseStoredCertificate(certificate)
+	if err != nil {
+		return certificate.UUID, ""
+	}
+	fingerprint := stored.fingerprint()
+	if certificate.AuthorityUuid == "" || certificate.Engine == "" {
+		return certificate.UUID, fingerprint
+	}
+	stored.meta.Engine = certificate.Engine
+	stored.meta.IssuerId = certificate.IssuerId
+	return getCertificateUuid(certificate.AuthorityUuid, stored.meta, certificate.SerialNumber, fingerprint), fingerprint
+}
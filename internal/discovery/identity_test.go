@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestGetCertificateUuid(t *testing.T) {
+	pki := certificateMeta{Engine: "pki", IssuerId: "issuer-1"}
+	kv := certificateMeta{Engine: "kv", SecretPath: "tls/server", SecretField: "certificate"}
+	uuid := getCertificateUuid("authority", pki, "01-02", "fingerprint")
+
+	tests := []struct {
+		name         string
+		authority    string
+		meta         certificateMeta
+		serialNumber string
+		fingerprint  string
+		same         bool
+	}{
+		{name: "same certificate", authority: "authority", meta: pki, serialNumber: "01-02", fingerprint: "fingerprint", same: true},
+		{name: "fingerprint ignored for PKI engine", authority: "authority", meta: pki, serialNumber: "01-02", fingerprint: "other", same: true},
+		{name: "other authority", authority: "other", meta: pki, serialNumber: "01-02", fingerprint: "fingerprint"},
+		{name: "other engine", authority: "authority", meta: certificateMeta{Engine: "pki-int", IssuerId: "issuer-1"}, serialNumber: "01-02", fingerprint: "fingerprint"},
+		{name: "other issuer", authority: "authority", meta: certificateMeta{Engine: "pki", IssuerId: "issuer-2"}, serialNumber: "01-02", fingerprint: "fingerprint"},
+		{name: "other serial number", authority: "authority", meta: pki, serialNumber: "01-03", fingerprint: "fingerprint"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if same := getCertificateUuid(test.authority, test.meta, test.serialNumber, test.fingerprint) == uuid; same != test.same {
+				t.Fatalf("expected same uuid %v, got %v", test.same, same)
+			}
+		})
+	}
+
+	kvUuid := getCertificateUuid("authority", kv, "01-02", "fingerprint")
+	if kvUuid != utils.DeterministicGUID("authority", "kv", "fingerprint") {
+		t.Fatalf("expected KV certificate to be identified by its fingerprint, got %s", kvUuid)
+	}
+	moved := kv
+	moved.SecretPath = "tls/other"
+	if getCertificateUuid("authority", moved, "03", "fingerprint") != kvUuid {
+		t.Fatal("expected KV certificate to keep its uuid in another secret")
+	}
+}
+
+func TestGetStoredCertificateIdentity(t *testing.T) {
+	parsed := createCertificate(t, "example.com", nil, time.Now().AddDate(1, 0, 0))
+	content := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: parsed.Raw}))
+	hash := sha256.Sum256(parsed.Raw)
+	fingerprint := hex.EncodeToString(hash[:])
+
+	tests := []struct {
+		name        string
+		certificate db.Certificate
+		uuid        string
+		fingerprint string
+	}{
+		{
+			name:        "PKI certificate",
+			certificate: db.Certificate{UUID: "legacy", AuthorityUuid: "authority", Engine: "pki", IssuerId: "issuer-1", SerialNumber: "01-02", Base64Content: content},
+			uuid:        utils.DeterministicGUID("authority", "pki", "issuer-1", "01-02"),
+			fingerprint: fingerprint,
+		},
+		{
+			name: "KV certificate",
+			certificate: db.Certificate{UUID: "legacy", AuthorityUuid: "authority", Engine: "kv", SerialNumber: "01-02", Base64Content: content,
+				Meta: []byte(`{"engine":"kv","secretPath":"tls/server","secretField":"certificate"}`)},
+			uuid:        utils.DeterministicGUID("authority", "kv", fingerprint),
+			fingerprint: fingerprint,
+		},
+		{
+			name:        "unknown authority",
+			certificate: db.Certificate{UUID: "legacy", Engine: "pki", SerialNumber: "01-02", Base64Content: content},
+			uuid:        "legacy",
+			fingerprint: fingerprint,
+		},
+		{
+			name:        "unknown engine",
+			certificate: db.Certificate{UUID: "legacy", AuthorityUuid: "authority", SerialNumber: "01-02", Base64Content: content},
+			uuid:        "legacy",
+			fingerprint: fingerprint,
+		},
+		{
+			name:        "invalid content",
+			certificate: db.Certificate{UUID: "legacy", AuthorityUuid: "authority", Engine: "pki", SerialNumber: "01-02", Base64Content: "not base64!"},
+			uuid:        "legacy",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uuid, fingerprint := getStoredCertificateIdentity(&test.certificate)
+			if uuid != test.uuid || fingerprint != test.fingerprint {
+				t.Fatalf("expected %s with fingerprint %q, got %s with fingerprint %q", test.uuid, test.fingerprint, uuid, fingerprint)
+			}
+		})
+	}
+}
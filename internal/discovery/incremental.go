@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// discoverKnownCertificates associates the certificates of the engine read by the previous incremental discovery
+// to the discovery and refreshes their revocation from the revoked certificates of the engine.
+// The serial numbers of the certificates which were not read yet, or which are not stored anymore, are returned.
+func (s *DiscoveryAPIService) discoverKnownCertificates(ctx context.Context, authorityUuid string, engine string, keys []string, revoked map[string]bool, filter *certificateFilter, issuers map[string]bool, writer *certificateWriter, p *progress) ([]string, error) {
+	serials, err := s.discoveryRepo.ListEngineSerials(authorityUuid, engine)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]db.EngineSerial, len(serials))
+	for _, serial := range serials {
+		known[serial.SerialNumber] = serial
+	}
+
+	var newKeys []string
+	var knownIds []uint
+	knownKeys := make(map[uint]string)
+	var revokedKeys []string
+	for _, key := range keys {
+		serial, ok := known[key]
+		if !ok {
+			newKeys = append(newKeys, key)
+			continue
+		}
+		knownIds = append(knownIds, serial.CertificateId)
+		knownKeys[serial.CertificateId] = key
+		if revoked[key] && !serial.Revoked {
+			revokedKeys = append(revokedKeys, key)
+		}
+	}
+	if len(revokedKeys) > 0 {
+		if err := s.discoveryRepo.MarkEngineSerialsRevoked(authorityUuid, engine, revokedKeys); err != nil {
+			return nil, err
+		}
+	}
+	s.log.With(zax.Get(ctx)...).Debug("Reusing certificates read by previous discovery", zap.String("engine", engine), zap.Int("known_certificates", len(knownIds)), zap.Int("new_certificates", len(newKeys)), zap.Int("newly_revoked", len(revokedKeys)))
+
+	for start := 0; start < len(knownIds); start += s.batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ids := knownIds[start:min(start+s.batchSize, len(knownIds))]
+		batch := ids
+		var missing []uint
+		if !filter.isEmpty() {
+			batch, missing, err = s.matchKnownCertificates(ctx, engine, batch, filter, issuers, p)
+			if err != nil {
+				return nil, err
+			}
+		}
+		associated, err := s.discoveryRepo.AssociateCertificateIdsToDiscovery(writer.discovery, batch)
+		if err != nil {
+			return nil, err
+		}
+		// certificates removed in the meantime by the garbage collection are read from the engine again
+		missing = append(missing, getMissingIds(batch, associated)...)
+		for _, id := range missing {
+			newKeys = append(newKeys, knownKeys[id])
+		}
+		// known certificates are counted as read, so that the progress of the discovery is estimated correctly
+		p.read.Add(int64(len(ids) - len(missing)))
+		p.known.Add(int64(len(associated)))
+	}
+	return newKeys, nil
+}
+
+// getMissingIds returns the ids which are not in the found ids.
+func getMissingIds(ids []uint, found []uint) []uint {
+	foundIds := make(map[uint]bool, len(found))
+	for _, id := range found {
+		foundIds[id] = true
+	}
+	var missing []uint
+	for _, id := range ids {
+		if !foundIds[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// matchKnownCertificates returns the IDs of the stored certificates which pass the filter and the IDs of the certificates
+// which are not stored anymore or can not be parsed, to be read from the engine again.
+func (s *DiscoveryAPIService) matchKnownCertificates(ctx context.Context, engine string, ids []uint, filter *certificateFilter, issuers map[string]bool, p *progress) ([]uint, []uint, error) {
+	certificates, err := s.discoveryRepo.FindCertificatesByIds(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	matched := make([]uint, 0, len(certificates))
+	parsed := make([]uint, 0, len(certificates))
+	for _, certificate := range certificates {
+		stored, err := parseStoredCertificate(certificate)
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Warn("Error parsing stored certificate", zap.String("certificate_key", certificate.SerialNumber), zap.String("engine", engine), zap.Error(err))
+			continue
+		}
+		parsed = append(parsed, certificate.Id)
+		if !filter.matches(stored, issuers) {
+			p.skipped.Add(1)
+			continue
+		}
+		matched = append(matched, certificate.Id)
+	}
+	return matched, getMissingIds(ids, parsed), nil
+}
+
+// parseStoredCertificate parses the certificate stored by the previous discovery.
+func parseStoredCertificate(certificate *db.Certificate) (*discoveredCertificate, error) {
+	content, err := base64.StdEncoding.DecodeString(certificate.Base64Content)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM certificate")
+	}
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := getCertificateMeta(certificate)
+	return &discoveredCertificate{
+		serialNumber: certificate.SerialNumber,
+		pem:          string(content),
+		certificate:  parsed,
+		meta:         meta,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	vault2 "github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// newIssuerCertificate creates the certificate of the issuer read from the PKI engine together with the issuer details.
+func newIssuerCertificate(engine string, data schema.PkiReadIssuerResponse) (*discoveredCertificate, error) {
+	block, _ := pem.Decode([]byte(data.Certificate))
+	if block == nil {
+		return nil, errors.New("failed to decode PEM certificate")
+	}
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	serialNumber, err := utils.ExtractSerialNumber(parsed.Raw)
+	if err != nil {
+		return nil, err
+	}
+	// the serial number has the format of the keys listed by the PKI engine, so that the issuer certificate listed
+	// with the certificates gets the same uuid
+	serialNumber = strings.ReplaceAll(serialNumber, ":", "-")
+
+	meta := newCertificateMeta(engine, parsed)
+	meta.CaIssuerId = data.IssuerId
+	meta.IssuerName = data.IssuerName
+	meta.KeyId = data.KeyId
+	meta.Usage = data.Usage
+	meta.Revoked = data.Revoked
+	if data.RevocationTime > 0 {
+		revocationTime := time.Unix(int64(data.RevocationTime), 0).UTC()
+		meta.RevocationTime = &revocationTime
+	}
+
+	return &discoveredCertificate{
+		serialNumber: serialNumber,
+		pem:          data.Certificate,
+		certificate:  parsed,
+		meta:         meta,
+	}, nil
+}
+
+// discoverIssuers reads the issuers of the PKI engine and stores their certificates. Issuers which can not be read
+// are reported and the discovery continues.
+func (s *DiscoveryAPIService) discoverIssuers(ctx context.Context, client *vault2.Client, authorityUuid string, engine string, filter *certificateFilter, issuers map[string]bool, writer *certificateWriter, p *progress) error {
+	release, err := s.runner.Acquire(ctx, authorityUuid)
+	if err != nil {
+		return err
+	}
+	response, err := client.Secrets.PkiListIssuers(ctx, vault2.WithMountPath(engine))
+	release()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Vault responds with not found when there are no issuers
+		if vault2.IsErrorStatus(err, http.StatusNotFound) {
+			return nil
+		}
+		s.log.With(zax.Get(ctx)...).Warn("Error listing issuers", zap.String("engine", engine), zap.Error(err))
+		s.recordFailure(ctx, writer.discovery, engine, "", fmt.Errorf("unable to list issuers: %v", err))
+		return nil
+	}
+	p.listed.Add(int64(len(response.Data.Keys)))
+
+	for _, issuerId := range response.Data.Keys {
+		s.log.With(zax.Get(ctx)...).Debug("Reading issuer", zap.String("issuer_id", issuerId), zap.String("engine", engine))
+		discovered, err := s.readIssuer(ctx, client, authorityUuid, engine, issuerId)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// issuer which can not be read is reported and the discovery continues
+			p.failed.Add(1)
+			s.log.With(zax.Get(ctx)...).Warn("Error reading issuer", zap.String("issuer_id", issuerId), zap.String("engine", engine), zap.Error(err))
+			s.recordFailure(ctx, writer.discovery, engine, issuerId, fmt.Errorf("unable to read issuer: %v", err))
+			continue
+		}
+		p.read.Add(1)
+		if !filter.matches(discovered, issuers) {
+			p.skipped.Add(1)
+			continue
+		}
+		if err := s.writeDiscoveredCertificate(ctx, engine, discovered, writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DiscoveryAPIService) readIssuer(ctx context.Context, client *vault2.Client, authorityUuid string, engine string, issuerId string) (*discoveredCertificate, error) {
+	release, err := s.runner.Acquire(ctx, authorityUuid)
+	if err != nil {
+		return nil, err
+	}
+	issuerData, err := client.Secrets.PkiReadIssuer(ctx, issuerId, vault2.WithMountPath(engine))
+	release()
+	if err != nil {
+		return nil, err
+	}
+	discovered, err := newIssuerCertificate(engine, issuerData.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the issuer certificate is identified by the issuer which signed it, the same as when it is listed with the certificates
+	release, err = s.runner.Acquire(ctx, authorityUuid)
+	if err != nil {
+		return nil, err
+	}
+	certificateData, err := client.Secrets.PkiReadCert(ctx, discovered.serialNumber, vault2.WithMountPath(engine))
+	release()
+	if err == nil {
+		discovered.meta.IssuerId = certificateData.Data.IssuerId
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return discovered, nil
+}
+
+// writeDiscoveredCertificate queues the certificate which passed the filter to be stored.
+func (s *DiscoveryAPIService) writeDiscoveredCertificate(ctx context.Context, engine string, discovered *discoveredCertificate, writer *certificateWriter) error {
+	certificate, err := discovered.toDbCertificate(writer.discovery.AuthorityUuid)
+	if err != nil {
+		return err
+	}
+	return writer.Write(ctx, engine, certificate)
+}
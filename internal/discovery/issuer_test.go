@@ -0,0 +1,75 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-client-go/schema"
+)
+
+func TestNewIssuerCertificate(t *testing.T) {
+	issuer := createCertificateFromTemplate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(0x0a0b0c),
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	})
+	revokedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	data := schema.PkiReadIssuerResponse{
+		Certificate:    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Raw})),
+		IssuerId:       "issuer-1",
+		IssuerName:     "root",
+		KeyId:          "key-1",
+		Usage:          "read-only,issuing-certificates,crl-signing",
+		Revoked:        true,
+		RevocationTime: int32(revokedAt.Unix()),
+	}
+
+	discovered, err := newIssuerCertificate("pki", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discovered.serialNumber != "0a-0b-0c" {
+		t.Fatalf("expected serial number in the format of the listed keys, got %s", discovered.serialNumber)
+	}
+	meta := discovered.meta
+	if meta.Engine != "pki" || meta.CaIssuerId != "issuer-1" || meta.IssuerName != "root" || meta.KeyId != "key-1" || meta.Subject != "CN=root" {
+		t.Fatalf("unexpected issuer details %+v", meta)
+	}
+	if !meta.Revoked || meta.RevocationTime == nil || !meta.RevocationTime.Equal(revokedAt) {
+		t.Fatalf("expected revocation at %v, got %v %v", revokedAt, meta.Revoked, meta.RevocationTime)
+	}
+
+	var usage []string
+	for _, attribute := range meta.toMetadata() {
+		if attribute.Uuid == model.CERTIFICATE_USAGE_META_ATTR {
+			for _, content := range attribute.Content {
+				usage = append(usage, content.GetData().(string))
+			}
+		}
+	}
+	if len(usage) != 3 || usage[0] != "read-only" || usage[1] != "issuing-certificates" || usage[2] != "crl-signing" {
+		t.Fatalf("unexpected usage %v", usage)
+	}
+
+	data.Revoked = false
+	data.RevocationTime = 0
+	discovered, err = newIssuerCertificate("pki", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discovered.meta.Revoked || discovered.meta.RevocationTime != nil {
+		t.Fatalf("expected issuer not revoked, got %v %v", discovered.meta.Revoked, discovered.meta.RevocationTime)
+	}
+
+	if _, err := newIssuerCertificate("pki", schema.PkiReadIssuerResponse{Certificate: "invalid"}); err == nil {
+		t.Fatal("expected error for invalid certificate")
+	}
+}
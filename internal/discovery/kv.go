@@ -0,0 +1,299 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	vault2 "github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultKvMaxDepth is the number of nested paths searched when the depth is not specified
+const defaultKvMaxDepth = 10
+
+// kvOptions are the KV secrets engines searched for certificates and the paths of the secrets to search
+type kvOptions struct {
+	Engines      []string `json:"engines"`
+	PathPrefixes []string `json:"pathPrefixes,omitempty"`
+	MaxDepth     int      `json:"maxDepth"`
+}
+
+// newKvOptions creates the options from the discovery attributes, or returns nil when no KV secrets engine is selected.
+func newKvOptions(attributes []model.Attribute) (*kvOptions, error) {
+	enginesAttr := model.GetAttributeFromArrayByUUID(model.DISCOVERY_KV_ENGINE_ATTR, attributes)
+	if enginesAttr == nil || len(enginesAttr.GetContent()) == 0 {
+		return nil, nil
+	}
+	options := &kvOptions{MaxDepth: defaultKvMaxDepth}
+	for _, engine := range enginesAttr.GetContent() {
+		engineData, _ := engine.GetData().(map[string]interface{})
+		engineName, _ := engineData["engineName"].(string)
+		if engineName != "" {
+			options.Engines = append(options.Engines, engineName)
+		}
+	}
+	for _, prefix := range strings.Split(getStringAttribute(model.DISCOVERY_KV_PATH_PREFIX_ATTR, attributes), ",") {
+		if prefix = strings.TrimLeft(strings.TrimSpace(prefix), "/"); prefix != "" {
+			options.PathPrefixes = append(options.PathPrefixes, prefix)
+		}
+	}
+	if attribute := model.GetAttributeFromArrayByUUID(model.DISCOVERY_KV_MAX_DEPTH_ATTR, attributes); attribute != nil && len(attribute.GetContent()) > 0 {
+		depth, _ := attribute.GetContent()[0].GetData().(int32)
+		if depth < 0 {
+			return nil, fmt.Errorf("maximum path depth must not be negative")
+		}
+		options.MaxDepth = int(depth)
+	}
+	return options, nil
+}
+
+// selected returns true when the secret, or the secrets below the path ending with slash, are to be searched.
+func (o *kvOptions) selected(path string) bool {
+	if len(o.PathPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range o.PathPrefixes {
+		if strings.HasPrefix(path, prefix) || (strings.HasSuffix(path, "/") && strings.HasPrefix(prefix, path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// listKvEngines returns the KV secrets engines with their version.
+func listKvEngines(ctx context.Context, client *vault2.Client) (map[string]int, error) {
+	//Due to the nature of its intended usage, there is no guarantee on backwards compatibility for this endpoint.
+	mounts, err := client.System.InternalUiListEnabledVisibleMounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	engines := make(map[string]int)
+	for engineName, engineData := range mounts.Data.Secret {
+		data, _ := engineData.(map[string]any)
+		if data["type"] != "kv" && data["type"] != "generic" {
+			continue
+		}
+		version := 1
+		if options, ok := data["options"].(map[string]any); ok && options["version"] == "2" {
+			version = 2
+		}
+		engines[strings.TrimSuffix(engineName, "/")] = version
+	}
+	return engines, nil
+}
+
+// kvCertificate is the certificate found in the field of the secret
+type kvCertificate struct {
+	field       string
+	certificate *x509.Certificate
+}
+
+// findKvCertificates returns the certificates stored in the fields of the secret, including the fields of nested objects.
+// Fields which hold invalid certificates are returned with the error.
+func findKvCertificates(data map[string]interface{}, parent string) ([]kvCertificate, map[string]error) {
+	var certificates []kvCertificate
+	failures := make(map[string]error)
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		name := field
+		if parent != "" {
+			name = parent + "." + field
+		}
+		switch value := data[field].(type) {
+		case string:
+			parsed, err := utils.ParseCertificatesValue(value)
+			if err != nil {
+				failures[name] = err
+				continue
+			}
+			for _, certificate := range parsed {
+				certificates = append(certificates, kvCertificate{field: name, certificate: certificate})
+			}
+		case map[string]interface{}:
+			nested, nestedFailures := findKvCertificates(value, name)
+			certificates = append(certificates, nested...)
+			for nestedName, err := range nestedFailures {
+				failures[nestedName] = err
+			}
+		}
+	}
+	return certificates, failures
+}
+
+// newKvDiscoveredCertificate creates the certificate found in the secret of the KV secrets engine.
+func newKvDiscoveredCertificate(engine string, path string, found kvCertificate) (*discoveredCertificate, error) {
+	serialNumber, err := utils.ExtractSerialNumber(found.certificate.Raw)
+	if err != nil {
+		return nil, err
+	}
+	meta := newCertificateMeta(engine, found.certificate)
+	meta.SecretPath = path
+	meta.SecretField = found.field
+	return &discoveredCertificate{
+		serialNumber: serialNumber,
+		pem:          string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: found.certificate.Raw})),
+		certificate:  found.certificate,
+		meta:         meta,
+	}, nil
+}
+
+// discoverKvEngine walks the secrets of the KV secrets engine and searches them for certificates in parallel
+// by the workers of the runner.
+func (s *DiscoveryAPIService) discoverKvEngine(ctx context.Context, client *vault2.Client, authority *db.AuthorityInstance, engine string, version int, options *kvOptions, filter *certificateFilter, writer *certificateWriter, p *progress) error {
+	g, gctx := errgroup.WithContext(ctx)
+	queue := make(chan string)
+	g.Go(func() error {
+		defer close(queue)
+		keys, err := s.listKvSecrets(gctx, client, authority.UUID, engine, version, "")
+		if err != nil {
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+			// engine which can not be listed is reported and the discovery continues with the next engine
+			s.log.With(zax.Get(ctx)...).Warn("Error listing secrets", zap.String("engine", engine), zap.Error(err))
+			p.enginesFailed.Add(1)
+			s.recordFailure(ctx, writer.discovery, engine, "", err)
+			return nil
+		}
+		p.enginesListed.Add(1)
+		return s.walkKvSecrets(gctx, client, authority.UUID, engine, version, "", keys, 0, options, queue, writer, p)
+	})
+	for i := 0; i < s.runner.Workers(); i++ {
+		g.Go(func() error {
+			for path := range queue {
+				s.log.With(zax.Get(ctx)...).Debug("Reading secret", zap.String("secret_path", path), zap.String("engine", engine))
+				data, err := s.readKvSecret(gctx, client, authority.UUID, engine, version, path)
+				if err != nil {
+					if gctx.Err() != nil {
+						return gctx.Err()
+					}
+					// secret which can not be read is reported and the discovery continues
+					p.failed.Add(1)
+					s.log.With(zax.Get(ctx)...).Warn("Error reading secret", zap.String("secret_path", path), zap.String("engine", engine), zap.Error(err))
+					s.recordFailure(ctx, writer.discovery, engine, path, err)
+					continue
+				}
+				p.read.Add(1)
+				found, failures := findKvCertificates(data, "")
+				for field, err := range failures {
+					p.failed.Add(1)
+					s.log.With(zax.Get(ctx)...).Warn("Error parsing certificate", zap.String("secret_path", path), zap.String("secret_field", field), zap.String("engine", engine), zap.Error(err))
+					s.recordFailure(ctx, writer.discovery, engine, path, fmt.Errorf("field %s: %v", field, err))
+				}
+				for _, certificate := range found {
+					discovered, err := newKvDiscoveredCertificate(engine, path, certificate)
+					if err != nil {
+						return err
+					}
+					if !filter.matches(discovered, nil) {
+						p.skipped.Add(1)
+						continue
+					}
+					if err := s.writeDiscoveredCertificate(gctx, engine, discovered, writer); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// walkKvSecrets queues the selected secrets of the directory and walks its nested directories up to the maximum depth.
+func (s *DiscoveryAPIService) walkKvSecrets(ctx context.Context, client *vault2.Client, authorityUuid string, engine string, version int, directory string, keys []string, depth int, options *kvOptions, queue chan<- string, writer *certificateWriter, p *progress) error {
+	for _, key := range keys {
+		path := directory + key
+		if !options.selected(path) {
+			continue
+		}
+		if !strings.HasSuffix(key, "/") {
+			p.listed.Add(1)
+			select {
+			case queue <- path:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		if depth >= options.MaxDepth {
+			s.log.With(zax.Get(ctx)...).Debug("Maximum path depth reached", zap.String("secret_path", path), zap.String("engine", engine))
+			continue
+		}
+		nested, err := s.listKvSecrets(ctx, client, authorityUuid, engine, version, path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// directory which can not be listed is reported and the walk continues
+			s.log.With(zax.Get(ctx)...).Warn("Error listing secrets", zap.String("secret_path", path), zap.String("engine", engine), zap.Error(err))
+			s.recordFailure(ctx, writer.discovery, engine, path, err)
+			continue
+		}
+		if err := s.walkKvSecrets(ctx, client, authorityUuid, engine, version, path, nested, depth+1, options, queue, writer, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DiscoveryAPIService) listKvSecrets(ctx context.Context, client *vault2.Client, authorityUuid string, engine string, version int, path string) ([]string, error) {
+	release, err := s.runner.Acquire(ctx, authorityUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	var response *vault2.Response[schema.StandardListResponse]
+	if version == 2 {
+		response, err = client.Secrets.KvV2List(ctx, path, vault2.WithMountPath(engine))
+	} else {
+		response, err = client.Secrets.KvV1List(ctx, path, vault2.WithMountPath(engine))
+	}
+	if err != nil {
+		// Vault responds with not found when there are no secrets
+		if vault2.IsErrorStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return response.Data.Keys, nil
+}
+
+func (s *DiscoveryAPIService) readKvSecret(ctx context.Context, client *vault2.Client, authorityUuid string, engine string, version int, path string) (map[string]interface{}, error) {
+	release, err := s.runner.Acquire(ctx, authorityUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if version == 2 {
+		response, err := client.Secrets.KvV2Read(ctx, path, vault2.WithMountPath(engine))
+		if err != nil {
+			// the latest version of the secret is deleted
+			if vault2.IsErrorStatus(err, http.StatusNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return response.Data.Data, nil
+	}
+	response, err := client.Secrets.KvV1Read(ctx, path, vault2.WithMountPath(engine))
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
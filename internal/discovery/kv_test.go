@@ -0,0 +1,57 @@
+package discovery
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestFindKvCertificates(t *testing.T) {
+	notAfter := time.Now().AddDate(1, 0, 0)
+	server := createCertificate(t, "server", nil, notAfter)
+	client := createCertificate(t, "client", nil, notAfter)
+	root := createCertificate(t, "root", nil, notAfter)
+	serverPem := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Raw}))
+	rootPem := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw}))
+	invalidPem := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("invalid")}))
+
+	data := map[string]interface{}{
+		"certificate": serverPem + rootPem,
+		"password":    "secret",
+		"port":        8443,
+		"tls": map[string]interface{}{
+			"client": map[string]interface{}{
+				"certificate": base64.StdEncoding.EncodeToString(client.Raw),
+				"broken":      invalidPem,
+			},
+			"enabled": true,
+		},
+	}
+
+	certificates, failures := findKvCertificates(data, "")
+	expected := []struct {
+		field      string
+		commonName string
+	}{
+		{"certificate", "server"},
+		{"certificate", "root"},
+		{"tls.client.certificate", "client"},
+	}
+	if len(certificates) != len(expected) {
+		t.Fatalf("expected %d certificates, got %d", len(expected), len(certificates))
+	}
+	for i, e := range expected {
+		if certificates[i].field != e.field || certificates[i].certificate.Subject.CommonName != e.commonName {
+			t.Fatalf("expected %s in %s, got %s in %s", e.commonName, e.field, certificates[i].certificate.Subject.CommonName, certificates[i].field)
+		}
+	}
+	if len(failures) != 1 || failures["tls.client.broken"] == nil {
+		t.Fatalf("expected failure of tls.client.broken, got %v", failures)
+	}
+
+	certificates, failures = findKvCertificates(map[string]interface{}{"client": data["tls"]}, "secret")
+	if len(certificates) != 1 || certificates[0].field != "secret.client.client.certificate" || len(failures) != 1 {
+		t.Fatalf("unexpected certificates %v and failures %v with parent", certificates, failures)
+	}
+}
@@ -0,0 +1,238 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// discoveryOptions are the options of the discovery run, stored in the discovery meta
+type discoveryOptions struct {
+	PartialResults bool               `json:"partialResults,omitempty"`
+	Incremental    bool               `json:"incremental,omitempty"`
+	Kv             *kvOptions         `json:"kv,omitempty"`
+	IncludeIssuers bool               `json:"includeIssuers,omitempty"`
+	Filters        *certificateFilter `json:"filters,omitempty"`
+}
+
+func getDiscoveryOptions(discovery *db.Discovery) discoveryOptions {
+	var options discoveryOptions
+	if len(discovery.Meta) > 0 {
+		_ = json.Unmarshal(discovery.Meta, &options)
+	}
+	return options
+}
+
+func getBooleanAttribute(uuid string, attributes []model.Attribute) bool {
+	attribute := model.GetAttributeFromArrayByUUID(uuid, attributes)
+	if attribute == nil || len(attribute.GetContent()) == 0 {
+		return false
+	}
+	value, _ := attribute.GetContent()[0].GetData().(bool)
+	return value
+}
+
+func getStringAttribute(uuid string, attributes []model.Attribute) string {
+	attribute := model.GetAttributeFromArrayByUUID(uuid, attributes)
+	if attribute == nil || len(attribute.GetContent()) == 0 {
+		return ""
+	}
+	value, _ := attribute.GetContent()[0].GetData().(string)
+	return value
+}
+
+func metadataAttribute(uuid string, name string, label string, contentType model.AttributeContentType, content ...model.AttributeContent) model.MetadataAttribute {
+	return model.MetadataAttribute{
+		Uuid:        uuid,
+		Name:        name,
+		Content:     content,
+		Type:        model.META,
+		ContentType: contentType,
+		Properties: model.MetadataAttributeProperties{
+			Label:   label,
+			Visible: true,
+		},
+	}
+}
+
+// getProgressMetadata returns the progress counters of the discovery, with the estimated completion time while it is running.
+func getProgressMetadata(discovery *db.Discovery) []model.MetadataAttribute {
+	meta := []model.MetadataAttribute{
+		metadataAttribute(model.DISCOVERY_ENGINES_DONE_META_ATTR, "enginesDone", "Engines done", model.STRING,
+			model.StringAttributeContent{Data: strconv.Itoa(discovery.EnginesDone) + " of " + strconv.Itoa(discovery.EnginesTotal)}),
+		metadataAttribute(model.DISCOVERY_CERTIFICATES_LISTED_META_ATTR, "certificatesListed", "Certificates listed", model.INTEGER,
+			model.IntegerAttributeContent{Data: int32(discovery.CertificatesListed)}),
+		metadataAttribute(model.DISCOVERY_CERTIFICATES_READ_META_ATTR, "certificatesRead", "Certificates read", model.INTEGER,
+			model.IntegerAttributeContent{Data: int32(discovery.CertificatesRead)}),
+		metadataAttribute(model.DISCOVERY_CERTIFICATES_FAILED_META_ATTR, "certificatesFailed", "Certificates failed", model.INTEGER,
+			model.IntegerAttributeContent{Data: int32(discovery.CertificatesFailed)}),
+	}
+	if discovery.Status == "IN_PROGRESS" && discovery.EstimatedCompletionAt != nil {
+		meta = append(meta, metadataAttribute(model.DISCOVERY_ESTIMATED_COMPLETION_META_ATTR, "estimatedCompletion", "Estimated completion", model.DATETIME,
+			model.DateTimeAttributeContent{Data: *discovery.EstimatedCompletionAt}))
+	}
+	return meta
+}
+
+// certificateMeta is the metadata of the discovered certificate stored with the certificate
+type certificateMeta struct {
+	Engine         string     `json:"engine"`
+	IssuerId       string     `json:"issuerId,omitempty"`
+	Revoked        bool       `json:"revoked"`
+	RevocationTime *time.Time `json:"revocationTime,omitempty"`
+	Subject        string     `json:"subject,omitempty"`
+	NotBefore      *time.Time `json:"notBefore,omitempty"`
+	NotAfter       *time.Time `json:"notAfter,omitempty"`
+	SecretPath     string     `json:"secretPath,omitempty"`
+	SecretField    string     `json:"secretField,omitempty"`
+	CaIssuerId     string     `json:"caIssuerId,omitempty"`
+	IssuerName     string     `json:"issuerName,omitempty"`
+	KeyId          string     `json:"keyId,omitempty"`
+	Usage          string     `json:"usage,omitempty"`
+}
+
+func newCertificateMeta(engine string, certificate *x509.Certificate) certificateMeta {
+	notBefore := certificate.NotBefore
+	notAfter := certificate.NotAfter
+	return certificateMeta{
+		Engine:    engine,
+		Subject:   certificate.Subject.String(),
+		NotBefore: &notBefore,
+		NotAfter:  &notAfter,
+	}
+}
+
+func getCertificateMeta(certificate *db.Certificate) (certificateMeta, bool) {
+	var meta certificateMeta
+	if len(certificate.Meta) == 0 || json.Unmarshal(certificate.Meta, &meta) != nil {
+		return meta, false
+	}
+	return meta, true
+}
+
+// toMetadata returns the certificate metadata as metadata attributes.
+func (m certificateMeta) toMetadata() []model.MetadataAttribute {
+	meta := []model.MetadataAttribute{
+		metadataAttribute(model.CERTIFICATE_ENGINE_META_ATTR, "engine", "Secrets engine", model.STRING,
+			model.StringAttributeContent{Data: m.Engine}),
+	}
+	if m.IssuerId != "" {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_ISSUER_ID_META_ATTR, "issuerId", "Issuer ID", model.STRING,
+			model.StringAttributeContent{Data: m.IssuerId}))
+	}
+	meta = append(meta, metadataAttribute(model.CERTIFICATE_REVOKED_META_ATTR, "revoked", "Revoked", model.BOOLEAN,
+		model.BooleanAttributeContent{Data: m.Revoked}))
+	if m.RevocationTime != nil {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_REVOCATION_TIME_META_ATTR, "revocationTime", "Revocation time", model.DATETIME,
+			model.DateTimeAttributeContent{Data: *m.RevocationTime}))
+	}
+	if m.Subject != "" {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_SUBJECT_META_ATTR, "subject", "Subject", model.STRING,
+			model.StringAttributeContent{Data: m.Subject}))
+	}
+	if m.NotBefore != nil {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_NOT_BEFORE_META_ATTR, "notBefore", "Not before", model.DATETIME,
+			model.DateTimeAttributeContent{Data: *m.NotBefore}))
+	}
+	if m.NotAfter != nil {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_NOT_AFTER_META_ATTR, "notAfter", "Not after", model.DATETIME,
+			model.DateTimeAttributeContent{Data: *m.NotAfter}))
+	}
+	if m.SecretPath != "" {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_SECRET_PATH_META_ATTR, "secretPath", "Secret path", model.STRING,
+			model.StringAttributeContent{Data: m.SecretPath}))
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_SECRET_FIELD_META_ATTR, "secretField", "Secret field", model.STRING,
+			model.StringAttributeContent{Data: m.SecretField}))
+	}
+	if m.CaIssuerId != "" {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_CA_ISSUER_ID_META_ATTR, "caIssuerId", "CA issuer ID", model.STRING,
+			model.StringAttributeContent{Data: m.CaIssuerId}))
+	}
+	if m.IssuerName != "" {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_ISSUER_NAME_META_ATTR, "issuerName", "Issuer name", model.STRING,
+			model.StringAttributeContent{Data: m.IssuerName}))
+	}
+	if m.KeyId != "" {
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_KEY_ID_META_ATTR, "keyId", "Key ID", model.STRING,
+			model.StringAttributeContent{Data: m.KeyId}))
+	}
+	if m.Usage != "" {
+		var content []model.AttributeContent
+		for _, usage := range strings.Split(m.Usage, ",") {
+			content = append(content, model.StringAttributeContent{Data: usage})
+		}
+		meta = append(meta, metadataAttribute(model.CERTIFICATE_USAGE_META_ATTR, "usage", "Issuer usage", model.STRING, content...))
+	}
+	return meta
+}
+
+// maxReportedFailures limits the number of failures listed in the discovery meta
+const maxReportedFailures = 100
+
+// getDiscoveryStatus maps the status stored in the database to the discovery status.
+func getDiscoveryStatus(status string) model.DiscoveryStatus {
+	switch status {
+	case "IN_PROGRESS":
+		return model.IN_PROGRESS
+	case "FAILED":
+		return model.FAILED
+	case "WARNING", "CANCELLED":
+		return model.WARNING
+	case "TIMED_OUT", "INTERRUPTED":
+		return model.FAILED
+	}
+	return model.COMPLETED
+}
+
+// getStatusReasonMetadata returns the reason why the discovery was stopped, or nil when it was not stopped.
+func getStatusReasonMetadata(discovery *db.Discovery) *model.MetadataAttribute {
+	var reason string
+	switch discovery.Status {
+	case "CANCELLED":
+		reason = "Discovery was cancelled, the certificates discovered before are kept"
+	case "TIMED_OUT":
+		reason = "Discovery exceeded the maximum duration, the certificates discovered before are kept"
+	case "INTERRUPTED":
+		reason = "Discovery was interrupted by restart of the connector, the certificates discovered before are kept"
+	default:
+		return nil
+	}
+	attribute := metadataAttribute(model.DISCOVERY_STATUS_REASON_META_ATTR, "statusReason", "Status reason", model.STRING,
+		model.StringAttributeContent{Data: reason})
+	return &attribute
+}
+
+// getFailuresMetadata returns the engines and certificates which were not discovered with the reason, or nil when there are none.
+func (s *DiscoveryAPIService) getFailuresMetadata(ctx context.Context, discovery *db.Discovery) *model.MetadataAttribute {
+	failures, count, err := s.discoveryRepo.ListDiscoveryFailures(discovery, maxReportedFailures)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to list discovery failures", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+		return nil
+	}
+	if count == 0 {
+		return nil
+	}
+	var content []model.AttributeContent
+	for _, failure := range failures {
+		reference := failure.Engine
+		message := "Engine " + failure.Engine + ": " + failure.Reason
+		if failure.SerialNumber != "" {
+			reference = failure.Engine + "/" + failure.SerialNumber
+			message = "Certificate " + failure.SerialNumber + " of engine " + failure.Engine + ": " + failure.Reason
+		}
+		content = append(content, model.StringAttributeContent{Reference: reference, Data: message})
+	}
+	if count > int64(len(failures)) {
+		content = append(content, model.StringAttributeContent{Data: strconv.FormatInt(count-int64(len(failures)), 10) + " more failures not listed"})
+	}
+	attribute := metadataAttribute(model.DISCOVERY_FAILURES_META_ATTR, "failures", "Failures", model.STRING, content...)
+	return &attribute
+}
@@ -0,0 +1,67 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// maxItemsPerPage limits the number of certificates returned in single page
+const maxItemsPerPage = 1000
+
+// getPagination returns the requested page of the discovered certificates, or the reasons why the request is invalid.
+func getPagination(discoveryDataRequestDto model.DiscoveryDataRequestDto) (db.Pagination, []string) {
+	var errors []string
+	pagination := db.Pagination{
+		Page:  int(discoveryDataRequestDto.PageNumber),
+		Limit: int(discoveryDataRequestDto.ItemsPerPage),
+		Sort:  discoveryDataRequestDto.SortBy,
+		Desc:  discoveryDataRequestDto.SortDescending,
+	}
+	if discoveryDataRequestDto.ItemsPerPage < 1 || discoveryDataRequestDto.ItemsPerPage > maxItemsPerPage {
+		errors = append(errors, "Items per page must be between 1 and "+strconv.Itoa(maxItemsPerPage))
+	}
+	if discoveryDataRequestDto.Cursor != "" {
+		cursor, err := decodeCursor(discoveryDataRequestDto.Cursor)
+		if err != nil {
+			errors = append(errors, "Invalid cursor")
+		}
+		pagination.Cursor = cursor
+	} else if discoveryDataRequestDto.PageNumber < 1 {
+		errors = append(errors, "Page number must be greater than 0")
+	}
+	if pagination.Sort != "" && !db.IsSortSupported(pagination.Sort) {
+		errors = append(errors, "Unsupported sort field "+pagination.Sort+", supported are "+strings.Join(db.SORTS, ", "))
+	}
+	return pagination, errors
+}
+
+func encodeCursor(cursor *db.Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*db.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor db.Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// getNextCursorMetadata returns the cursor of the next page, or nil when the page is the last one.
+func getNextCursorMetadata(page *db.Pagination) *model.MetadataAttribute {
+	if page.Next == nil {
+		return nil
+	}
+	attribute := metadataAttribute(model.DISCOVERY_NEXT_CURSOR_META_ATTR, "nextCursor", "Next page cursor", model.STRING,
+		model.StringAttributeContent{Data: encodeCursor(page.Next)})
+	return &attribute
+}
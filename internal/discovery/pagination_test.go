@@ -0,0 +1,71 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"testing"
+)
+
+func TestGetPagination(t *testing.T) {
+	cursor := encodeCursor(&db.Cursor{Value: "2026-10-19T10:00:00Z", Id: 42})
+	tests := []struct {
+		name      string
+		request   model.DiscoveryDataRequestDto
+		errors    int
+		cursorId  uint
+		page      int
+		limit     int
+		sortField string
+	}{
+		{name: "page", request: model.DiscoveryDataRequestDto{PageNumber: 2, ItemsPerPage: 10}, page: 2, limit: 10},
+		{name: "sorted page", request: model.DiscoveryDataRequestDto{PageNumber: 1, ItemsPerPage: 10, SortBy: db.SORT_NOT_AFTER}, page: 1, limit: 10, sortField: db.SORT_NOT_AFTER},
+		{name: "cursor without page number", request: model.DiscoveryDataRequestDto{ItemsPerPage: maxItemsPerPage, Cursor: cursor}, limit: maxItemsPerPage, cursorId: 42},
+		{name: "missing page number", request: model.DiscoveryDataRequestDto{ItemsPerPage: 10}, errors: 1, limit: 10},
+		{name: "zero items per page", request: model.DiscoveryDataRequestDto{PageNumber: 1}, errors: 1, page: 1},
+		{name: "too many items per page", request: model.DiscoveryDataRequestDto{PageNumber: 1, ItemsPerPage: maxItemsPerPage + 1}, errors: 1, page: 1, limit: maxItemsPerPage + 1},
+		{name: "invalid cursor", request: model.DiscoveryDataRequestDto{ItemsPerPage: 10, Cursor: "not a cursor"}, errors: 1, limit: 10},
+		{name: "unsupported sort", request: model.DiscoveryDataRequestDto{PageNumber: 1, ItemsPerPage: 10, SortBy: "issuer"}, errors: 1, page: 1, limit: 10, sortField: "issuer"},
+		{name: "all invalid", request: model.DiscoveryDataRequestDto{SortBy: "issuer"}, errors: 3, sortField: "issuer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pagination, errors := getPagination(test.request)
+			if len(errors) != test.errors {
+				t.Fatalf("expected %d errors, got %v", test.errors, errors)
+			}
+			if pagination.Page != test.page || pagination.Limit != test.limit || pagination.Sort != test.sortField {
+				t.Fatalf("unexpected pagination %+v", pagination)
+			}
+			if test.cursorId == 0 && pagination.Cursor != nil {
+				t.Fatalf("unexpected cursor %+v", pagination.Cursor)
+			}
+			if test.cursorId != 0 && (pagination.Cursor == nil || pagination.Cursor.Id != test.cursorId) {
+				t.Fatalf("expected cursor with id %d, got %+v", test.cursorId, pagination.Cursor)
+			}
+		})
+	}
+}
+
+func TestCursorEncoding(t *testing.T) {
+	cursors := []db.Cursor{
+		{},
+		{Value: "01:23:45", Id: 1},
+		{Value: "CN=example.com, O=Example \"Org\"", Id: 4294967295},
+	}
+	for _, expected := range cursors {
+		encoded := encodeCursor(&expected)
+		decoded, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode cursor %q: %v", encoded, err)
+		}
+		if *decoded != expected {
+			t.Fatalf("expected cursor %+v, got %+v", expected, *decoded)
+		}
+	}
+
+	for _, value := range []string{"not base64!", "bm90IGpzb24"} {
+		if cursor, err := decodeCursor(value); err == nil {
+			t.Fatalf("expected error for cursor %q, got %+v", value, cursor)
+		}
+	}
+}
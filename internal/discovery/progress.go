@@ -0,0 +1,124 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// progressInterval is the interval of storing the progress of the running discovery
+const progressInterval = 5 * time.Second
+
+// progress counts the engines and certificates processed by the running discovery
+type progress struct {
+	startedAt     time.Time
+	enginesTotal  int
+	enginesListed atomic.Int64
+	enginesDone   atomic.Int64
+	enginesFailed atomic.Int64
+	listed        atomic.Int64
+	read          atomic.Int64
+	failed        atomic.Int64
+	skipped       atomic.Int64
+	known         atomic.Int64
+}
+
+func newProgress(enginesTotal int) *progress {
+	return &progress{
+		startedAt:    time.Now(),
+		enginesTotal: enginesTotal,
+	}
+}
+
+// apply copies the counters to the discovery and estimates the completion time
+// from the rate of processed certificates so far.
+func (p *progress) apply(discovery *db.Discovery) {
+	discovery.EnginesTotal = p.enginesTotal
+	discovery.EnginesDone = int(p.enginesDone.Load())
+	discovery.CertificatesListed = int(p.listed.Load())
+	discovery.CertificatesRead = int(p.read.Load())
+	discovery.CertificatesFailed = int(p.failed.Load())
+	startedAt := p.startedAt
+	discovery.StartedAt = &startedAt
+	discovery.EstimatedCompletionAt = p.estimateCompletion()
+	heartbeatAt := time.Now()
+	discovery.HeartbeatAt = &heartbeatAt
+}
+
+// restore continues counting from the progress stored by the interrupted discovery.
+func (p *progress) restore(discovery *db.Discovery, enginesDone int) {
+	if discovery.StartedAt != nil {
+		p.startedAt = *discovery.StartedAt
+	}
+	p.enginesDone.Store(int64(enginesDone))
+	p.enginesListed.Store(int64(enginesDone))
+	p.listed.Store(int64(discovery.CertificatesListed))
+	p.read.Store(int64(discovery.CertificatesRead))
+	p.failed.Store(int64(discovery.CertificatesFailed))
+}
+
+func (p *progress) estimateCompletion() *time.Time {
+	enginesListed := p.enginesListed.Load()
+	processed := p.read.Load() + p.failed.Load()
+	if enginesListed == 0 || processed == 0 {
+		return nil
+	}
+	// engines not listed yet are expected to hold the same number of certificates as the listed ones on average
+	estimatedTotal := float64(p.listed.Load()) * float64(p.enginesTotal) / float64(enginesListed)
+	remaining := max(estimatedTotal-float64(processed), 0)
+	elapsed := time.Since(p.startedAt)
+	completion := time.Now().Add(time.Duration(float64(elapsed) * remaining / float64(processed)))
+	return &completion
+}
+
+// trackProgress stores the progress of the discovery periodically until the returned function is called.
+// The returned function stores the final progress.
+func (s *DiscoveryAPIService) trackProgress(ctx context.Context, discovery *db.Discovery, p *progress) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.storeProgress(ctx, discovery, p)
+				s.checkCancelRequest(ctx, discovery)
+			case <-done:
+				return
+			}
+		}
+	}()
+	s.storeProgress(ctx, discovery, p)
+	return func() {
+		close(done)
+		<-stopped
+		s.storeProgress(ctx, discovery, p)
+	}
+}
+
+func (s *DiscoveryAPIService) storeProgress(ctx context.Context, discovery *db.Discovery, p *progress) {
+	snapshot := db.Discovery{Id: discovery.Id}
+	p.apply(&snapshot)
+	if err := s.discoveryRepo.UpdateDiscoveryProgress(&snapshot); err != nil {
+		s.log.With(zax.Get(ctx)...).Warn("Unable to store discovery progress", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+	}
+}
+
+// checkCancelRequest stops the discovery when its cancel was requested on another instance of the connector.
+func (s *DiscoveryAPIService) checkCancelRequest(ctx context.Context, discovery *db.Discovery) {
+	requested, err := s.discoveryRepo.IsDiscoveryCancelRequested(discovery)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Warn("Unable to check discovery cancel request", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+		return
+	}
+	if requested {
+		s.log.With(zax.Get(ctx)...).Info("Cancelling discovery requested on another instance", zap.String("discovery_uuid", discovery.UUID))
+		s.runner.Cancel(discovery.UUID, errDiscoveryCancelled)
+	}
+}
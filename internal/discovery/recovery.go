@@ -0,0 +1,72 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/config"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recoveryKey identifies the recovery of the interrupted discoveries in the runner
+const recoveryKey = "discovery-recovery"
+
+// RecoverDiscoveries resumes or fails the discoveries interrupted by restart of the connector in the background,
+// depending on the mode. Discovery is interrupted when it did not store its progress for the grace period.
+func (s *DiscoveryAPIService) RecoverDiscoveries(mode string) {
+	gracePeriod := s.gracePeriod
+	s.runner.Go(recoveryKey, func(ctx context.Context) {
+		s.recoverStaleDiscoveries(ctx, mode, gracePeriod)
+		// discoveries which stored the progress just before the restart become stale after the grace period
+		select {
+		case <-time.After(gracePeriod):
+		case <-ctx.Done():
+			return
+		}
+		s.recoverStaleDiscoveries(ctx, mode, gracePeriod)
+	})
+}
+
+func (s *DiscoveryAPIService) recoverStaleDiscoveries(ctx context.Context, mode string, gracePeriod time.Duration) {
+	before := time.Now().Add(-gracePeriod)
+	discoveries, err := s.discoveryRepo.ListStaleDiscoveries(before)
+	if err != nil {
+		s.log.Error("Unable to list interrupted discoveries", zap.Error(err))
+		return
+	}
+	for i := range discoveries {
+		discovery := &discoveries[i]
+		if ctx.Err() != nil {
+			return
+		}
+		if s.runner.Running(discovery.UUID) {
+			continue
+		}
+		// the discovery can be claimed by another instance of the connector in the meantime
+		claimed, err := s.discoveryRepo.ClaimStaleDiscovery(discovery, before)
+		if err != nil {
+			s.log.Error("Unable to claim interrupted discovery", zap.String("discovery_uuid", discovery.UUID), zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		// discoveries created before the engines were stored can not be resumed
+		if mode != config.DISCOVERY_RESUME || discovery.AuthorityUuid == "" {
+			s.log.Warn("Failing interrupted discovery", zap.String("discovery_uuid", discovery.UUID))
+			s.updateDiscoveryStatus(ctx, discovery, "INTERRUPTED")
+			continue
+		}
+		authority, err := s.authorityRepo.FindAuthorityInstanceByUUID(discovery.AuthorityUuid)
+		if err != nil {
+			s.log.Warn("Authority of interrupted discovery not found", zap.String("discovery_uuid", discovery.UUID), zap.String("authority_uuid", discovery.AuthorityUuid), zap.Error(err))
+			s.updateDiscoveryStatus(ctx, discovery, "INTERRUPTED")
+			continue
+		}
+		s.log.Info("Resuming interrupted discovery", zap.String("discovery_uuid", discovery.UUID), zap.String("authority_uuid", authority.UUID))
+		s.runner.Go(discovery.UUID, func(runCtx context.Context) {
+			s.DiscoveryCertificates(runCtx, authority, discovery)
+		})
+	}
+}
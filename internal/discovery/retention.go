@@ -0,0 +1,61 @@
+package discovery
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// collectorKey identifies the garbage collection of the discoveries and certificates in the runner
+const collectorKey = "discovery-gc"
+
+// orphanedCertificatesChunk limits the number of certificates deleted by single statement
+const orphanedCertificatesChunk = 1000
+
+// CollectGarbage periodically deletes the finished discoveries older than the retention, or exceeding the number
+// of discoveries kept per authority, and the certificates which do not belong to any discovery. Zero retention
+// or count keeps the discoveries, zero interval disables the collection.
+func (s *DiscoveryAPIService) CollectGarbage(retention time.Duration, retentionCount int, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.runner.Every(collectorKey, interval, func(ctx context.Context) {
+		s.collectGarbage(ctx, retention, retentionCount)
+	})
+}
+
+func (s *DiscoveryAPIService) collectGarbage(ctx context.Context, retention time.Duration, retentionCount int) {
+	if retention > 0 {
+		deleted, err := s.discoveryRepo.DeleteExpiredDiscoveries(time.Now().Add(-retention))
+		if err != nil {
+			s.log.Error("Unable to delete expired discoveries", zap.Error(err))
+		} else if deleted > 0 {
+			s.log.Info("Deleted expired discoveries", zap.Int64("discoveries", deleted))
+		}
+	}
+	if retentionCount > 0 {
+		deleted, err := s.discoveryRepo.DeleteExcessDiscoveries(retentionCount)
+		if err != nil {
+			s.log.Error("Unable to delete excess discoveries", zap.Error(err))
+		} else if deleted > 0 {
+			s.log.Info("Deleted discoveries exceeding retention count", zap.Int64("discoveries", deleted))
+		}
+	}
+
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := s.discoveryRepo.DeleteOrphanedCertificates(orphanedCertificatesChunk)
+		if err != nil {
+			s.log.Error("Unable to delete orphaned certificates", zap.Error(err))
+			break
+		}
+		total += deleted
+		if deleted < orphanedCertificatesChunk {
+			break
+		}
+	}
+	if total > 0 {
+		s.log.Info("Deleted certificates not belonging to any discovery", zap.Int64("certificates", total))
+	}
+}
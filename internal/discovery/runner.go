@@ -0,0 +1,211 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	// errDiscoveryCancelled is the cause of the discovery stopped by the cancel request
+	errDiscoveryCancelled = errors.New("discovery cancelled")
+	// errDiscoveryDeleted is the cause of the discovery stopped because it is deleted
+	errDiscoveryDeleted = errors.New("discovery deleted")
+	// errDiscoveryTimeout is the cause of the discovery stopped after the maximum duration
+	errDiscoveryTimeout = errors.New("discovery exceeded maximum duration")
+	// errShutdown is the cause of the discovery stopped on shutdown
+	errShutdown = errors.New("connector shutdown")
+)
+
+// Runner runs the discoveries in the background and throttles the requests sent to Vault.
+// The number of concurrent requests and the request rate are limited per authority,
+// so that parallel discoveries against the same Vault share the same limits.
+type Runner struct {
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	wg          sync.WaitGroup
+	workers     int
+	rateLimit   float64
+	maxDuration time.Duration
+
+	mu        sync.Mutex
+	throttles map[string]*throttle
+	runs      map[string]*run
+}
+
+// run is the running discovery which can be cancelled
+type run struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+type throttle struct {
+	limiter   *rate.Limiter
+	semaphore chan struct{}
+}
+
+// NewRunner creates the runner with the number of concurrent workers and the number of requests per second
+// allowed per authority. Rate limit lower or equal to zero means no limit. Discoveries running longer than
+// the maximum duration are stopped, zero maximum duration means no limit.
+func NewRunner(workers int, rateLimit float64, maxDuration time.Duration) *Runner {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &Runner{
+		ctx:         ctx,
+		cancel:      cancel,
+		workers:     max(workers, 1),
+		rateLimit:   rateLimit,
+		maxDuration: maxDuration,
+		throttles:   make(map[string]*throttle),
+		runs:        make(map[string]*run),
+	}
+}
+
+// Workers returns the number of workers used to process single discovery.
+func (r *Runner) Workers() int {
+	return r.workers
+}
+
+// Go runs the discovery identified by the key in the background. The context passed to the function is cancelled
+// on shutdown, by Stop, or after the maximum duration. The reason is available by context.Cause.
+func (r *Runner) Go(key string, f func(ctx context.Context)) {
+	r.start(key, func(ctx context.Context) {
+		if r.maxDuration > 0 {
+			var stop context.CancelFunc
+			ctx, stop = context.WithTimeoutCause(ctx, r.maxDuration, errDiscoveryTimeout)
+			defer stop()
+		}
+		f(ctx)
+	})
+}
+
+// Every runs the job identified by the key in the background repeatedly with the interval, until shutdown or Stop.
+// The job is not limited by the maximum duration of the discovery.
+func (r *Runner) Every(key string, interval time.Duration, f func(ctx context.Context)) {
+	r.start(key, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			f(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+func (r *Runner) start(key string, f func(ctx context.Context)) {
+	ctx, cancel := context.WithCancelCause(r.ctx)
+	current := &run{cancel: cancel, done: make(chan struct{})}
+	r.mu.Lock()
+	r.runs[key] = current
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			if r.runs[key] == current {
+				delete(r.runs, key)
+			}
+			r.mu.Unlock()
+			cancel(nil)
+			close(current.done)
+		}()
+		f(ctx)
+	}()
+}
+
+// Running returns true when the discovery identified by the key is running.
+func (r *Runner) Running(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.runs[key]
+	return ok
+}
+
+// Stop stops the discovery identified by the key with the cause and waits for it to finish, or until the context is done.
+func (r *Runner) Stop(ctx context.Context, key string, cause error) error {
+	current := r.cancelRun(key, cause)
+	if current == nil {
+		return nil
+	}
+	select {
+	case <-current.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel stops the discovery identified by the key with the cause without waiting for it to finish.
+// It returns false when the discovery is not running.
+func (r *Runner) Cancel(key string, cause error) bool {
+	return r.cancelRun(key, cause) != nil
+}
+
+func (r *Runner) cancelRun(key string, cause error) *run {
+	r.mu.Lock()
+	current, ok := r.runs[key]
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	current.cancel(cause)
+	return current
+}
+
+// Shutdown cancels all running discoveries and waits for them to finish, or until the context is done.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.cancel(errShutdown)
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Acquire waits until the request to the Vault of the authority is allowed. The returned function must be called
+// when the request is finished.
+func (r *Runner) Acquire(ctx context.Context, authorityUuid string) (func(), error) {
+	t := r.throttle(authorityUuid)
+	select {
+	case t.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := t.limiter.Wait(ctx); err != nil {
+		<-t.semaphore
+		return nil, err
+	}
+	return func() { <-t.semaphore }, nil
+}
+
+func (r *Runner) throttle(authorityUuid string) *throttle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.throttles[authorityUuid]
+	if !ok {
+		limit := rate.Inf
+		if r.rateLimit > 0 {
+			limit = rate.Limit(r.rateLimit)
+		}
+		t = &throttle{
+			limiter:   rate.NewLimiter(limit, r.workers),
+			semaphore: make(chan struct{}, r.workers),
+		}
+		r.throttles[authorityUuid] = t
+	}
+	return t
+}
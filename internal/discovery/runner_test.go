@@ -0,0 +1,103 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// startRun runs the function blocked until its context is done and returns the channel with the cause.
+func startRun(r *Runner, key string) <-chan error {
+	cause := make(chan error, 1)
+	started := make(chan struct{})
+	r.Go(key, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		cause <- context.Cause(ctx)
+	})
+	<-started
+	return cause
+}
+
+func TestRunnerStop(t *testing.T) {
+	r := NewRunner(1, 0, 0)
+	cause := startRun(r, "discovery")
+	if !r.Running("discovery") {
+		t.Fatal("expected discovery to be running")
+	}
+	if err := r.Stop(context.Background(), "discovery", errDiscoveryCancelled); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-cause; !errors.Is(err, errDiscoveryCancelled) {
+		t.Fatalf("expected cancelled cause, got %v", err)
+	}
+	if r.Running("discovery") {
+		t.Fatal("expected discovery to be stopped")
+	}
+	if err := r.Stop(context.Background(), "unknown", errDiscoveryCancelled); err != nil {
+		t.Fatalf("expected no error for unknown discovery, got %v", err)
+	}
+}
+
+func TestRunnerStopTimeout(t *testing.T) {
+	r := NewRunner(1, 0, 0)
+	finish := make(chan struct{})
+	started := make(chan struct{})
+	r.Go("discovery", func(ctx context.Context) {
+		close(started)
+		<-finish
+	})
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.Stop(ctx, "discovery", errDiscoveryDeleted); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	close(finish)
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	r := NewRunner(1, 0, 0)
+	cause := startRun(r, "discovery")
+	if !r.Cancel("discovery", errDiscoveryCancelled) {
+		t.Fatal("expected running discovery to be cancelled")
+	}
+	if err := <-cause; !errors.Is(err, errDiscoveryCancelled) {
+		t.Fatalf("expected cancelled cause, got %v", err)
+	}
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r.Cancel("discovery", errDiscoveryCancelled) {
+		t.Fatal("expected finished discovery not to be cancelled")
+	}
+}
+
+func TestRunnerMaxDuration(t *testing.T) {
+	r := NewRunner(1, 0, 10*time.Millisecond)
+	cause := startRun(r, "discovery")
+	if err := <-cause; !errors.Is(err, errDiscoveryTimeout) {
+		t.Fatalf("expected timeout cause, got %v", err)
+	}
+}
+
+func TestRunnerShutdown(t *testing.T) {
+	r := NewRunner(1, 0, 0)
+	first := startRun(r, "first")
+	second := startRun(r, "second")
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, cause := range []<-chan error{first, second} {
+		if err := <-cause; !errors.Is(err, errShutdown) {
+			t.Fatalf("expected shutdown cause, got %v", err)
+		}
+	}
+	if r.Running("first") || r.Running("second") {
+		t.Fatal("expected all discoveries to be stopped")
+	}
+}
@@ -0,0 +1,84 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/utils"
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// schedulerKey identifies the scheduler of the recurring discoveries in the runner
+const schedulerKey = "discovery-scheduler"
+
+// schedulerInterval is how often the due schedules are checked, the cron expressions have minute precision
+const schedulerInterval = 30 * time.Second
+
+// RunSchedules starts the discoveries of the schedules in the background when they are due. Runs missed while
+// the connector was stopped are started once, and the run is skipped while the previous run is still in progress.
+func (s *DiscoveryAPIService) RunSchedules() {
+	s.runner.Every(schedulerKey, schedulerInterval, s.runDueSchedules)
+}
+
+func (s *DiscoveryAPIService) runDueSchedules(ctx context.Context) {
+	now := time.Now().UTC()
+	schedules, err := s.discoveryRepo.ListDueSchedules(now)
+	if err != nil {
+		s.log.Error("Unable to list due discovery schedules", zap.Error(err))
+		return
+	}
+	for i := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		s.runSchedule(ctx, &schedules[i], now)
+	}
+}
+
+func (s *DiscoveryAPIService) runSchedule(ctx context.Context, schedule *db.DiscoverySchedule, now time.Time) {
+	log := s.log.With(zap.String("schedule_uuid", schedule.UUID), zap.String("schedule_name", schedule.Name))
+	var nextRunAt *time.Time
+	cron, err := utils.ParseCron(schedule.Cron)
+	if err != nil {
+		log.Error("Invalid cron expression of schedule", zap.String("cron", schedule.Cron), zap.Error(err))
+	} else if next := cron.Next(now); !next.IsZero() {
+		nextRunAt = &next
+	}
+	// the run can be claimed by another instance of the connector in the meantime
+	claimed, err := s.discoveryRepo.ClaimScheduleRun(schedule, nextRunAt)
+	if err != nil {
+		log.Error("Unable to claim run of schedule", zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	runs, _, err := s.discoveryRepo.ListScheduleRuns(schedule, 1)
+	if err != nil {
+		log.Error("Unable to get last run of schedule", zap.Error(err))
+		return
+	}
+	if len(runs) > 0 && runs[0].Status == "IN_PROGRESS" {
+		log.Warn("Skipping run of schedule, previous run is still in progress", zap.String("discovery_uuid", runs[0].UUID))
+		return
+	}
+
+	request := model.DiscoveryRequestDto{
+		Name:       schedule.Name,
+		Kind:       schedule.Kind,
+		Attributes: model.UnmarshalAttributesValues([]byte(schedule.Attributes)),
+	}
+	if getAuthorityUuid(request.Attributes) == "" {
+		log.Error("Authority of schedule not specified")
+		return
+	}
+	log.Info("Starting scheduled discovery", zap.String("discovery_name", request.Name))
+	response := s.startDiscovery(ctx, request, schedule)
+	if response.Code != http.StatusOK {
+		log.Error("Unable to start scheduled discovery", zap.Int("status", response.Code), zap.Any("response", response.Body))
+	}
+}
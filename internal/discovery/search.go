@@ -0,0 +1,135 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// parsingChunk is the number of certificates parsed and updated at once
+const parsingChunk = 500
+
+// SearchCertificates - Search the discovered certificates
+func (s *DiscoveryAPIService) SearchCertificates(ctx context.Context, request model.CertificateSearchRequestDto) (model.ImplResponse, error) {
+	pagination, errs := getPagination(model.DiscoveryDataRequestDto{
+		PageNumber:     request.PageNumber,
+		ItemsPerPage:   request.ItemsPerPage,
+		SortBy:         request.SortBy,
+		SortDescending: request.SortDescending,
+		Cursor:         request.Cursor,
+	})
+	if request.ExpiringWithinDays < 0 {
+		errs = append(errs, "Number of days to expiration must not be negative")
+	}
+	if len(errs) > 0 {
+		return model.Response(http.StatusUnprocessableEntity, errs), nil
+	}
+	search := db.CertificateSearch{
+		AuthorityUuid:      request.AuthorityUuid,
+		Engine:             request.Engine,
+		CommonName:         request.CommonName,
+		San:                request.San,
+		IssuerDn:           request.Issuer,
+		KeyAlgorithm:       request.KeyAlgorithm,
+		KeySize:            request.KeySize,
+		SignatureAlgorithm: request.SignatureAlgorithm,
+		Fingerprint:        request.Fingerprint,
+		NotAfterFrom:       request.NotAfterFrom,
+		NotAfterTo:         request.NotAfterTo,
+	}
+	if request.ExpiringWithinDays > 0 {
+		now := time.Now()
+		expiresBefore := now.AddDate(0, 0, request.ExpiringWithinDays)
+		if search.NotAfterFrom == nil || search.NotAfterFrom.Before(now) {
+			search.NotAfterFrom = &now
+		}
+		if search.NotAfterTo == nil || search.NotAfterTo.After(expiresBefore) {
+			search.NotAfterTo = &expiresBefore
+		}
+	}
+	if request.DiscoveryUuid != "" {
+		discovery, err := s.discoveryRepo.FindDiscoveryByUUID(request.DiscoveryUuid)
+		if err != nil {
+			return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Discovery " + request.DiscoveryUuid + " not found."}), nil
+		}
+		search.DiscoveryId = discovery.Id
+	}
+
+	page, err := s.discoveryRepo.SearchCertificates(search, pagination)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to search certificates", zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to search certificates"}), nil
+	}
+	response := model.CertificateSearchResponseDto{
+		TotalItems:   page.TotalRows,
+		TotalPages:   int64(page.TotalPages),
+		Certificates: make([]model.CertificateSearchResultDto, 0),
+	}
+	if page.Next != nil {
+		response.NextCursor = encodeCursor(page.Next)
+	}
+	certificates, _ := page.Rows.([]*db.Certificate)
+	for _, certificate := range certificates {
+		response.Certificates = append(response.Certificates, model.CertificateSearchResultDto{
+			Uuid:               certificate.UUID,
+			SerialNumber:       certificate.SerialNumber,
+			AuthorityUuid:      certificate.AuthorityUuid,
+			Engine:             certificate.Engine,
+			CommonName:         certificate.CommonName,
+			Subject:            certificate.Subject,
+			Sans:               certificate.Sans,
+			Issuer:             certificate.IssuerDn,
+			NotBefore:          certificate.NotBefore,
+			NotAfter:           certificate.NotAfter,
+			KeyAlgorithm:       certificate.KeyAlgorithm,
+			KeySize:            certificate.KeySize,
+			SignatureAlgorithm: certificate.SignatureAlgorithm,
+			Fingerprint:        certificate.Fingerprint,
+			Base64Content:      certificate.Base64Content,
+		})
+	}
+	return model.Response(http.StatusOK, response), nil
+}
+
+// parseStoredCertificates parses the columns of the certificates stored before the columns were added,
+// so that they can be searched.
+func (s *DiscoveryAPIService) parseStoredCertificates(ctx context.Context) {
+	parsed := 0
+	for ctx.Err() == nil {
+		certificates, err := s.discoveryRepo.ListUnparsedCertificates(parsingChunk)
+		if err != nil {
+			s.log.Error("Unable to list certificates to parse", zap.Error(err))
+			return
+		}
+		for _, certificate := range certificates {
+			parseStoredColumns(certificate)
+		}
+		if err := s.discoveryRepo.UpdateParsedColumns(certificates); err != nil {
+			s.log.Error("Unable to store parsed columns of certificates", zap.Error(err))
+			return
+		}
+		parsed += len(certificates)
+		if len(certificates) < parsingChunk {
+			break
+		}
+	}
+	if parsed > 0 {
+		s.log.Info("Parsed columns of stored certificates", zap.Int("certificates", parsed))
+	}
+}
+
+// parseStoredColumns sets the parsed columns of the stored certificate, the certificate which can not be parsed
+// gets empty key algorithm, so that it is not parsed again.
+func parseStoredColumns(certificate *db.Certificate) {
+	certificate.KeyAlgorithm = ""
+	stored, err := parseStoredCertificate(certificate)
+	if err != nil {
+		return
+	}
+	setParsedColumns(certificate, stored.certificate)
+}
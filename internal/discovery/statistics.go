@@ -0,0 +1,173 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/model"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yuseferi/zax/v2"
+	"go.uber.org/zap"
+)
+
+// statisticsMaxAge is how long the cached statistics are used, so that the expiration does not drift too far
+const statisticsMaxAge = time.Hour
+
+// statisticsCache holds the statistics of the authorities calculated from their latest completed discovery.
+// The statistics are calculated again when another discovery of the authority completes, also by another instance.
+type statisticsCache struct {
+	mu          sync.Mutex
+	authorities map[string]cachedStatistics
+}
+
+type cachedStatistics struct {
+	discoveryId uint
+	statistics  model.AuthorityStatisticsDto
+}
+
+func newStatisticsCache() *statisticsCache {
+	return &statisticsCache{authorities: make(map[string]cachedStatistics)}
+}
+
+// get returns the statistics of the authority if they were calculated after its latest discovery completed.
+func (c *statisticsCache) get(authorityUuid string, discoveryId uint) (model.AuthorityStatisticsDto, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.authorities[authorityUuid]
+	if !ok || cached.discoveryId != discoveryId || time.Since(cached.statistics.CalculatedAt) > statisticsMaxAge {
+		return model.AuthorityStatisticsDto{}, false
+	}
+	return cached.statistics, true
+}
+
+func (c *statisticsCache) put(discoveryId uint, statistics model.AuthorityStatisticsDto) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authorities[statistics.AuthorityUuid] = cachedStatistics{discoveryId: discoveryId, statistics: statistics}
+}
+
+func (c *statisticsCache) remove(authorityUuid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.authorities, authorityUuid)
+}
+
+// ListStatistics - List certificate statistics of the authorities with completed Discovery
+func (s *DiscoveryAPIService) ListStatistics(ctx context.Context) (model.ImplResponse, error) {
+	discoveries, err := s.discoveryRepo.FindLatestCompletedDiscoveries("")
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to find latest discoveries", zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to get statistics"}), nil
+	}
+	dtos := make([]model.AuthorityStatisticsDto, 0, len(discoveries))
+	for i := range discoveries {
+		statistics, err := s.getStatistics(&discoveries[i])
+		if err != nil {
+			s.log.With(zax.Get(ctx)...).Error("Unable to calculate statistics", zap.String("authority_uuid", discoveries[i].AuthorityUuid), zap.Error(err))
+			return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to get statistics"}), nil
+		}
+		dtos = append(dtos, statistics)
+	}
+	return model.Response(http.StatusOK, dtos), nil
+}
+
+// GetStatistics - Get certificate statistics of the authority
+func (s *DiscoveryAPIService) GetStatistics(ctx context.Context, authorityUuid string) (model.ImplResponse, error) {
+	if _, err := s.authorityRepo.FindAuthorityInstanceByUUID(authorityUuid); err != nil {
+		return model.Response(http.StatusNotFound, model.ErrorMessageDto{Message: "Authority " + authorityUuid + " not found."}), nil
+	}
+	discoveries, err := s.discoveryRepo.FindLatestCompletedDiscoveries(authorityUuid)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to find latest discovery", zap.String("authority_uuid", authorityUuid), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to get statistics of authority " + authorityUuid}), nil
+	}
+	if len(discoveries) == 0 {
+		return model.Response(http.StatusOK, model.AuthorityStatisticsDto{
+			AuthorityUuid: authorityUuid,
+			CalculatedAt:  time.Now(),
+			Engines:       []model.EngineStatisticsDto{},
+		}), nil
+	}
+	statistics, err := s.getStatistics(&discoveries[0])
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Error("Unable to calculate statistics", zap.String("authority_uuid", authorityUuid), zap.Error(err))
+		return model.Response(http.StatusInternalServerError, model.ErrorMessageDto{Message: "Unable to get statistics of authority " + authorityUuid}), nil
+	}
+	return model.Response(http.StatusOK, statistics), nil
+}
+
+// getStatistics returns the cached statistics of the authority of the latest completed discovery, or calculates them.
+func (s *DiscoveryAPIService) getStatistics(latest *db.Discovery) (model.AuthorityStatisticsDto, error) {
+	if statistics, ok := s.statistics.get(latest.AuthorityUuid, latest.Id); ok {
+		return statistics, nil
+	}
+	statistics, err := s.calculateStatistics(latest.AuthorityUuid)
+	if err != nil {
+		return model.AuthorityStatisticsDto{}, err
+	}
+	s.statistics.put(latest.Id, statistics)
+	return statistics, nil
+}
+
+// refreshStatistics calculates the statistics of the authority of the completed discovery.
+func (s *DiscoveryAPIService) refreshStatistics(ctx context.Context, discovery *db.Discovery) {
+	s.statistics.remove(discovery.AuthorityUuid)
+	statistics, err := s.calculateStatistics(discovery.AuthorityUuid)
+	if err != nil {
+		s.log.With(zax.Get(ctx)...).Warn("Unable to calculate statistics", zap.String("authority_uuid", discovery.AuthorityUuid), zap.Error(err))
+		return
+	}
+	s.statistics.put(discovery.Id, statistics)
+}
+
+func (s *DiscoveryAPIService) calculateStatistics(authorityUuid string) (model.AuthorityStatisticsDto, error) {
+	dto := model.AuthorityStatisticsDto{
+		AuthorityUuid: authorityUuid,
+		CalculatedAt:  time.Now(),
+		Engines:       []model.EngineStatisticsDto{},
+	}
+	engines, err := s.discoveryRepo.GetEngineStatistics(authorityUuid)
+	if err != nil {
+		return dto, err
+	}
+	keyAlgorithms, err := s.discoveryRepo.GetKeyAlgorithmStatistics(authorityUuid)
+	if err != nil {
+		return dto, err
+	}
+	keyAlgorithmsByEngine := make(map[string][]model.KeyAlgorithmCountDto)
+	for _, keyAlgorithm := range keyAlgorithms {
+		keyAlgorithmsByEngine[keyAlgorithm.Engine] = append(keyAlgorithmsByEngine[keyAlgorithm.Engine], model.KeyAlgorithmCountDto{
+			KeyAlgorithm: keyAlgorithm.KeyAlgorithm,
+			KeySize:      keyAlgorithm.KeySize,
+			Count:        keyAlgorithm.Count,
+		})
+	}
+	for _, engine := range engines {
+		engineDto := model.EngineStatisticsDto{
+			Engine:              engine.Engine,
+			Kind:                engine.Kind,
+			DiscoveryUuid:       engine.DiscoveryUuid,
+			DiscoveredAt:        engine.DiscoveredAt,
+			TotalCertificates:   engine.Total,
+			RevokedCertificates: engine.Revoked,
+			Expiration: []model.ExpirationBucketDto{
+				{Bucket: model.EXPIRATION_EXPIRED, Count: engine.Expired},
+				{Bucket: model.EXPIRATION_7_DAYS, Count: engine.ExpiringIn7Days},
+				{Bucket: model.EXPIRATION_30_DAYS, Count: engine.ExpiringIn30Days},
+				{Bucket: model.EXPIRATION_90_DAYS, Count: engine.ExpiringIn90Days},
+				{Bucket: model.EXPIRATION_365_DAYS, Count: engine.ExpiringIn365Days},
+				{Bucket: model.EXPIRATION_LATER, Count: engine.ExpiringLater},
+			},
+			KeyAlgorithms: keyAlgorithmsByEngine[engine.Engine],
+		}
+		if engineDto.KeyAlgorithms == nil {
+			engineDto.KeyAlgorithms = []model.KeyAlgorithmCountDto{}
+		}
+		dto.TotalCertificates += engine.Total
+		dto.RevokedCertificates += engine.Revoked
+		dto.Engines = append(dto.Engines, engineDto)
+	}
+	return dto, nil
+}
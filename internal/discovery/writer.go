@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"context"
+	"sync"
+)
+
+// certificateStore stores the certificates written by the certificateWriter, implemented by db.DiscoveryRepository
+type certificateStore interface {
+	AddCertificatesToDiscovery(discovery *db.Discovery, certificates []*db.Certificate) error
+	AddEngineSerials(serials []db.EngineSerial) error
+}
+
+// certificateWriter stores the discovered certificates in batches in the background,
+// so that the certificates of the engine are not held in memory.
+type certificateWriter struct {
+	repo         certificateStore
+	discovery    *db.Discovery
+	batchSize    int
+	certificates chan pendingCertificate
+	done         chan struct{}
+
+	// authorityUuid is set when the stored certificates are remembered for the incremental discovery
+	authorityUuid string
+
+	mu      sync.Mutex
+	err     error
+	written int
+}
+
+// pendingCertificate is the certificate of the engine waiting to be stored. Without the certificate,
+// it requests to store the certificates queued before and to close the flushed channel.
+type pendingCertificate struct {
+	engine      string
+	certificate *db.Certificate
+	flushed     chan struct{}
+}
+
+func newCertificateWriter(repo certificateStore, discovery *db.Discovery, batchSize int) *certificateWriter {
+	w := &certificateWriter{
+		repo:         repo,
+		discovery:    discovery,
+		batchSize:    batchSize,
+		certificates: make(chan pendingCertificate, batchSize),
+		done:         make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// rememberSerials makes the writer remember the stored certificates of the authority for the incremental discovery.
+func (w *certificateWriter) rememberSerials(authorityUuid string) {
+	w.authorityUuid = authorityUuid
+}
+
+// Write queues the certificate of the engine to be stored. The error of previously stored batch is returned.
+func (w *certificateWriter) Write(ctx context.Context, engine string, certificate *db.Certificate) error {
+	if err := w.Err(); err != nil {
+		return err
+	}
+	select {
+	case w.certificates <- pendingCertificate{engine: engine, certificate: certificate}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush waits until the certificates queued so far are stored and returns the first error which occurred.
+func (w *certificateWriter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case w.certificates <- pendingCertificate{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return w.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stores the remaining certificates and returns the first error which occurred.
+func (w *certificateWriter) Close() error {
+	close(w.certificates)
+	<-w.done
+	return w.Err()
+}
+
+func (w *certificateWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Written returns the number of certificates stored so far.
+func (w *certificateWriter) Written() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+func (w *certificateWriter) run() {
+	defer close(w.done)
+	batch := make([]pendingCertificate, 0, w.batchSize)
+	for certificate := range w.certificates {
+		if certificate.certificate == nil {
+			w.flush(batch)
+			batch = make([]pendingCertificate, 0, w.batchSize)
+			close(certificate.flushed)
+			continue
+		}
+		batch = append(batch, certificate)
+		if len(batch) >= w.batchSize {
+			w.flush(batch)
+			batch = make([]pendingCertificate, 0, w.batchSize)
+		}
+	}
+	w.flush(batch)
+}
+
+func (w *certificateWriter) flush(batch []pendingCertificate) {
+	if len(batch) == 0 || w.Err() != nil {
+		return
+	}
+	certificates := make([]*db.Certificate, 0, len(batch))
+	for _, pending := range batch {
+		certificates = append(certificates, pending.certificate)
+	}
+	err := w.repo.AddCertificatesToDiscovery(w.discovery, certificates)
+	if err == nil && w.authorityUuid != "" {
+		err = w.repo.AddEngineSerials(w.engineSerials(batch))
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.err = err
+		return
+	}
+	w.written += len(batch)
+}
+
+// engineSerials returns the stored certificates of the batch to be remembered by the incremental discovery.
+func (w *certificateWriter) engineSerials(batch []pendingCertificate) []db.EngineSerial {
+	serials := make([]db.EngineSerial, 0, len(batch))
+	for _, pending := range batch {
+		meta, _ := getCertificateMeta(pending.certificate)
+		serials = append(serials, db.EngineSerial{
+			AuthorityUuid: w.authorityUuid,
+			Engine:        pending.engine,
+			SerialNumber:  pending.certificate.SerialNumber,
+			CertificateId: pending.certificate.Id,
+			Revoked:       meta.Revoked,
+		})
+	}
+	return serials
+}
@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"CZERTAINLY-HashiCorp-Vault-Connector/internal/db"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeCertificateStore records the stored batches and fails the batch with the configured number
+type fakeCertificateStore struct {
+	mu        sync.Mutex
+	batches   [][]*db.Certificate
+	serials   []db.EngineSerial
+	failBatch int
+	err       error
+}
+
+func (f *fakeCertificateStore) AddCertificatesToDiscovery(_ *db.Discovery, certificates []*db.Certificate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, certificates)
+	if f.err != nil && len(f.batches) == f.failBatch {
+		return f.err
+	}
+	return nil
+}
+
+func (f *fakeCertificateStore) AddEngineSerials(serials []db.EngineSerial) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.serials = append(f.serials, serials...)
+	return nil
+}
+
+func (f *fakeCertificateStore) batchSizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sizes []int
+	for _, batch := range f.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func writeCertificates(t *testing.T, w *certificateWriter, count int) {
+	for i := 0; i < count; i++ {
+		if err := w.Write(context.Background(), "pki", &db.Certificate{SerialNumber: string(rune('a' + i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCertificateWriterBatches(t *testing.T) {
+	store := &fakeCertificateStore{}
+	w := newCertificateWriter(store, &db.Discovery{}, 2)
+	w.rememberSerials("authority")
+
+	writeCertificates(t, w, 3)
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sizes := store.batchSizes(); len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 1 {
+		t.Fatalf("expected batches of 2 and 1 certificates after flush, got %v", sizes)
+	}
+	if w.Written() != 3 {
+		t.Fatalf("expected 3 written certificates, got %d", w.Written())
+	}
+
+	writeCertificates(t, w, 1)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Written() != 4 || len(store.serials) != 4 {
+		t.Fatalf("expected 4 written and remembered certificates, got %d and %d", w.Written(), len(store.serials))
+	}
+	if store.serials[0].AuthorityUuid != "authority" || store.serials[0].Engine != "pki" || store.serials[0].SerialNumber != "a" {
+		t.Fatalf("unexpected remembered serial %+v", store.serials[0])
+	}
+}
+
+func TestCertificateWriterError(t *testing.T) {
+	storeErr := errors.New("database unavailable")
+	store := &fakeCertificateStore{failBatch: 1, err: storeErr}
+	w := newCertificateWriter(store, &db.Discovery{}, 2)
+
+	writeCertificates(t, w, 2)
+	if err := w.Flush(context.Background()); !errors.Is(err, storeErr) {
+		t.Fatalf("expected store error from flush, got %v", err)
+	}
+	if err := w.Write(context.Background(), "pki", &db.Certificate{}); !errors.Is(err, storeErr) {
+		t.Fatalf("expected store error from write, got %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, storeErr) {
+		t.Fatalf("expected store error from close, got %v", err)
+	}
+	if w.Written() != 0 || len(store.batchSizes()) != 1 {
+		t.Fatalf("expected no certificates stored after the error, got %d in %v", w.Written(), store.batchSizes())
+	}
+}
@@ -28,8 +28,59 @@ const (
 	RA_PROFILE_AUTHORITY_ATTR string = "5af5693a-74bf-4ec4-b101-44ce35d8455b"
 
 	// Discovery Attributes
-	DISCOVERY_AUTHORITY_ATTR  string = "24531b64-efd2-4a16-8ba8-ffef90890356"
-	DISCOVERY_PKI_ENGINE_ATTR string = "12a10e1e-1fdf-4ca5-b65f-68d92ef905a0"
+	DISCOVERY_AUTHORITY_ATTR       string = "24531b64-efd2-4a16-8ba8-ffef90890356"
+	DISCOVERY_PKI_ENGINE_ATTR      string = "12a10e1e-1fdf-4ca5-b65f-68d92ef905a0"
+	DISCOVERY_PARTIAL_RESULTS_ATTR string = "d4469dde-66d7-436d-997f-ee5266ce3c31"
+	DISCOVERY_INCREMENTAL_ATTR     string = "e9c89bb3-31c9-46fe-a4da-e383564c95c4"
+	DISCOVERY_INCLUDE_ISSUERS_ATTR string = "88d2d9f9-5a1c-4f0d-9d2d-b07b4a3e24a4"
+	DISCOVERY_SKIP_EXPIRED_ATTR    string = "245509cc-bbb6-4aa1-9276-8be48a31f219"
+	DISCOVERY_SKIP_REVOKED_ATTR    string = "a542e981-e1d3-401f-9c08-344363368ac2"
+	DISCOVERY_EXPIRING_WITHIN_ATTR string = "07b2b115-ec83-4793-8901-cd0a0a3f2912"
+	DISCOVERY_SUBJECT_PATTERN_ATTR string = "747b2c37-c33d-4866-a50b-d9c3bc9e3d43"
+	DISCOVERY_ISSUERS_ATTR         string = "b85039b7-2596-4f63-bbec-19984d841720"
+	DISCOVERY_KV_ENGINE_ATTR       string = "132556e0-7307-4421-b455-af69418050c1"
+	DISCOVERY_KV_PATH_PREFIX_ATTR  string = "2e75d97d-bb61-4ce4-af33-1a1f59b5eb83"
+	DISCOVERY_KV_MAX_DEPTH_ATTR    string = "9b9094f9-93ff-4a5f-aa11-17619bdb4fa0"
+
+	// Discovery Metadata Attributes
+	DISCOVERY_ENGINES_DONE_META_ATTR         string = "9d529a2c-4a8c-4069-a236-767fcd459fcb"
+	DISCOVERY_CERTIFICATES_LISTED_META_ATTR  string = "ad895886-7f1a-4a13-8c96-84a542e692bf"
+	DISCOVERY_CERTIFICATES_READ_META_ATTR    string = "df0fba00-6e80-479a-8981-6fc742863539"
+	DISCOVERY_CERTIFICATES_FAILED_META_ATTR  string = "66f1da62-f7d7-415c-b8ea-4b9e26ce568b"
+	DISCOVERY_ESTIMATED_COMPLETION_META_ATTR string = "a18d8b0d-7ed7-49c0-92ab-c6102db2b9eb"
+	DISCOVERY_FAILURES_META_ATTR             string = "916c308a-d2a4-4ed0-adfa-9a4b63546fc0"
+	DISCOVERY_FILTERS_META_ATTR              string = "bd1e6fb0-a089-4d45-b199-4c886f20d5d8"
+	DISCOVERY_STATUS_REASON_META_ATTR        string = "3f16a4e4-c97f-45e8-8020-96f2e2d048a2"
+	DISCOVERY_NEXT_CURSOR_META_ATTR          string = "b3275207-76d5-4f59-8130-ea1ef042750e"
+
+	// Discovered Certificate Metadata Attributes
+	CERTIFICATE_ENGINE_META_ATTR          string = "6441ca30-0f8c-4d44-997c-7d853736de33"
+	CERTIFICATE_ISSUER_ID_META_ATTR       string = "6c46e235-7fc0-4039-bbe9-f9d035679a1d"
+	CERTIFICATE_REVOKED_META_ATTR         string = "98369c8c-e344-4d32-beb9-3185ef7f5e5c"
+	CERTIFICATE_REVOCATION_TIME_META_ATTR string = "390fd23c-3bc8-4b18-b62b-3ab1861345b6"
+	CERTIFICATE_SUBJECT_META_ATTR         string = "12b57726-d2a1-4614-bc3b-44dd147a887a"
+	CERTIFICATE_NOT_BEFORE_META_ATTR      string = "d6aa3feb-585d-459e-b1ae-01530e8bfa7d"
+	CERTIFICATE_NOT_AFTER_META_ATTR       string = "f316b6e9-7138-40e3-a94d-3a273bb0b0ef"
+	CERTIFICATE_SECRET_PATH_META_ATTR     string = "b66634bb-7d77-478d-9466-813b6c132c12"
+	CERTIFICATE_SECRET_FIELD_META_ATTR    string = "aee019bd-bd0c-42c2-bc06-5349c557c135"
+	CERTIFICATE_ISSUER_NAME_META_ATTR     string = "75573379-48c6-406e-b182-745850a953d3"
+	CERTIFICATE_CA_ISSUER_ID_META_ATTR    string = "fead1465-37b9-436a-b58f-c116bf6f558f"
+	CERTIFICATE_KEY_ID_META_ATTR          string = "a5a959ca-6075-4946-9c9c-ca9897b2069a"
+	CERTIFICATE_USAGE_META_ATTR           string = "c247952b-cd6f-42fb-bf5c-89fd652470a2"
+
+	// Issue Certificate Attributes
+	ISSUE_CERTIFICATE_TTL_ATTR          string = "e328e926-b8cc-4d86-b8f5-cfc1eb9fdc79"
+	ISSUE_CERTIFICATE_CHAIN_FORMAT_ATTR string = "9a0c5f3e-7d41-4b8a-a6e2-3c51d0f7b214"
+
+	// Certificate Metadata Attributes
+	CERTIFICATE_CA_CHAIN_META_ATTR string = "0b9e4d2a-61c7-4f35-9d08-b7a2e5c13f46"
+	CERTIFICATE_PKCS7_META_ATTR    string = "c4f17a85-2e3b-4d69-8a10-5f9e6b2d7c03"
+)
+
+const (
+	CHAIN_FORMAT_NONE     string = "none"
+	CHAIN_FORMAT_METADATA string = "metadata"
+	CHAIN_FORMAT_PKCS7    string = "pkcs7"
 )
 
 type AttributeName string
@@ -88,6 +139,7 @@ const (
 	AuthorityManagementAttributes string = "AuthorityManagementAttributes"
 	DisoveryAttributes            string = "DiscoveryAttributes"
 	RAProfilesAttributes          string = "RAProfilesAttributes"
+	IssueCertificateAttributes    string = "IssueCertificateAttributes"
 )
 
 func GetAttributeListBySet(attributeSet string) []Attribute {
@@ -98,6 +150,8 @@ func GetAttributeListBySet(attributeSet string) []Attribute {
 		return getDiscoveryAttributes()
 	case RAProfilesAttributes:
 		return getRAProfilesAttributes()
+	case IssueCertificateAttributes:
+		return getIssueCertificateAttributes()
 	}
 
 	return nil
@@ -106,6 +160,7 @@ func GetAttributeListBySet(attributeSet string) []Attribute {
 func GetAttributeList() []Attribute {
 	attributeList := append(getAuthorityManagementAttributes(), getDiscoveryAttributes()...)
 	attributeList = append(attributeList, getRAProfilesAttributes()...)
+	attributeList = append(attributeList, getIssueCertificateAttributes()...)
 	attributeList = append(attributeList, getAuthorityManagementAttributes()...)
 	return attributeList
 }
@@ -165,6 +220,13 @@ func unmarshalAttributeContent(content []byte, contentType AttributeContentType)
 			}
 			result = objectData
 		}
+	case INTEGER:
+		integerContent := IntegerAttributeContent{}
+		err := json.Unmarshal(content, &integerContent)
+		result = integerContent
+		if err != nil {
+			log.Error(err.Error(), zap.String("content", string(content)))
+		}
 	case BOOLEAN:
 		booleanContent := BooleanAttributeContent{}
 		err := json.Unmarshal(content, &booleanContent)
@@ -262,6 +324,10 @@ func UnmarshalAttributesValues(content []byte) []Attribute {
 	for _, attribute := range attributes.Array() {
 		def := GetAttributeByName(gjson.Get(attribute.Raw, "name").String())
 		attributeObject := unmarshalAttributeValue([]byte(attribute.Raw), def)
+		if attributeObject == nil {
+			// attribute is not defined by the connector, skip it
+			continue
+		}
 		result = append(result, attributeObject)
 	}
 	return result
@@ -376,6 +442,58 @@ func getRAProfilesAttributes() []Attribute {
 	}
 }
 
+func getIssueCertificateAttributes() []Attribute {
+	return []Attribute{
+		DataAttribute{
+			Uuid:        ISSUE_CERTIFICATE_TTL_ATTR,
+			Name:        "issue_ttl",
+			Description: "Requested validity of the certificate as number of seconds or duration with s, m, h or d suffix, e.g. 720h. If not provided, the default TTL of the role will be used",
+			Type:        DATA,
+			Content:     nil,
+			ContentType: STRING,
+			Properties: &DataAttributeProperties{
+				Label:       "TTL",
+				Visible:     true,
+				Group:       "",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        ISSUE_CERTIFICATE_CHAIN_FORMAT_ATTR,
+			Name:        "issue_chain_format",
+			Description: "Format of the issuer chain returned with the certificate. The chain can be returned as metadata with CA certificates ordered from the issuer to the root, as PKCS#7 bundle including the certificate, or not returned at all",
+			Type:        DATA,
+			Content: []AttributeContent{
+				StringAttributeContent{
+					Reference: "Metadata",
+					Data:      CHAIN_FORMAT_METADATA,
+				},
+				StringAttributeContent{
+					Reference: "PKCS#7 bundle",
+					Data:      CHAIN_FORMAT_PKCS7,
+				},
+				StringAttributeContent{
+					Reference: "None",
+					Data:      CHAIN_FORMAT_NONE,
+				},
+			},
+			ContentType: STRING,
+			Properties: &DataAttributeProperties{
+				Label:       "Issuer chain format",
+				Visible:     true,
+				Group:       "",
+				Required:    false,
+				ReadOnly:    false,
+				List:        true,
+				MultiSelect: false,
+			},
+		},
+	}
+}
+
 func getDiscoveryAttributes() []Attribute {
 	return []Attribute{
 		DataAttribute{
@@ -427,6 +545,232 @@ func getDiscoveryAttributes() []Attribute {
 				},
 			},
 		},
+		DataAttribute{
+			Uuid:        DISCOVERY_PARTIAL_RESULTS_ATTR,
+			Name:        "discovery_partial_results",
+			Description: "Return certificates discovered so far while the discovery is in progress",
+			Type:        DATA,
+			Content: []AttributeContent{
+				BooleanAttributeContent{
+					Data: false,
+				},
+			},
+			ContentType: BOOLEAN,
+			Properties: &DataAttributeProperties{
+				Label:       "Partial results",
+				Visible:     true,
+				Group:       "",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_INCREMENTAL_ATTR,
+			Name:        "discovery_incremental",
+			Description: "Read only certificates which were not read by the previous incremental discovery of the same authority and engine",
+			Type:        DATA,
+			Content: []AttributeContent{
+				BooleanAttributeContent{
+					Data: false,
+				},
+			},
+			ContentType: BOOLEAN,
+			Properties: &DataAttributeProperties{
+				Label:       "Incremental discovery",
+				Visible:     true,
+				Group:       "",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_INCLUDE_ISSUERS_ATTR,
+			Name:        "discovery_include_issuers",
+			Description: "Discover also the issuer certificates of the PKI secret engines",
+			Type:        DATA,
+			Content: []AttributeContent{
+				BooleanAttributeContent{
+					Data: false,
+				},
+			},
+			ContentType: BOOLEAN,
+			Properties: &DataAttributeProperties{
+				Label:       "Include issuers",
+				Visible:     true,
+				Group:       "",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_SKIP_EXPIRED_ATTR,
+			Name:        "discovery_skip_expired",
+			Description: "Do not discover certificates which are already expired",
+			Type:        DATA,
+			Content: []AttributeContent{
+				BooleanAttributeContent{
+					Data: false,
+				},
+			},
+			ContentType: BOOLEAN,
+			Properties: &DataAttributeProperties{
+				Label:       "Skip expired certificates",
+				Visible:     true,
+				Group:       "Filters",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_SKIP_REVOKED_ATTR,
+			Name:        "discovery_skip_revoked",
+			Description: "Do not discover certificates which are revoked",
+			Type:        DATA,
+			Content: []AttributeContent{
+				BooleanAttributeContent{
+					Data: false,
+				},
+			},
+			ContentType: BOOLEAN,
+			Properties: &DataAttributeProperties{
+				Label:       "Skip revoked certificates",
+				Visible:     true,
+				Group:       "Filters",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_EXPIRING_WITHIN_ATTR,
+			Name:        "discovery_expiring_within",
+			Description: "Discover only certificates expiring within the given number of days",
+			Type:        DATA,
+			Content:     nil,
+			ContentType: INTEGER,
+			Properties: &DataAttributeProperties{
+				Label:       "Expiring within days",
+				Visible:     true,
+				Group:       "Filters",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_SUBJECT_PATTERN_ATTR,
+			Name:        "discovery_subject_pattern",
+			Description: "Discover only certificates with common name or subject alternative name matching the regular expression",
+			Type:        DATA,
+			Content:     nil,
+			ContentType: STRING,
+			Properties: &DataAttributeProperties{
+				Label:       "Subject pattern",
+				Visible:     true,
+				Group:       "Filters",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_ISSUERS_ATTR,
+			Name:        "discovery_issuers",
+			Description: "Discover only certificates issued by the issuers with given IDs or names, separated by comma",
+			Type:        DATA,
+			Content:     nil,
+			ContentType: STRING,
+			Properties: &DataAttributeProperties{
+				Label:       "Issuers",
+				Visible:     true,
+				Group:       "Filters",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_KV_ENGINE_ATTR,
+			Name:        "kv_engines_to_discover",
+			Description: "Select KV secret engines to be searched for stored certificates. If none selected, KV secret engines are not searched",
+			Type:        DATA,
+			Content:     nil,
+			ContentType: OBJECT,
+			Properties: &DataAttributeProperties{
+				Label:       "KV secrets engines",
+				Visible:     true,
+				Group:       "KV secrets engines",
+				Required:    false,
+				ReadOnly:    false,
+				List:        true,
+				MultiSelect: true,
+			},
+			AttributeCallback: &AttributeCallback{
+				CallbackContext: "v1/discoveryProvider/{uuid}/kvengines/callback",
+				CallbackMethod:  "GET",
+				Mappings: []AttributeCallbackMapping{
+					{
+						From:                 "authority_to_discover.data.uuid",
+						AttributeType:        DATA,
+						AttributeContentType: STRING,
+						To:                   "uuid",
+						Targets: []AttributeValueTarget{
+							PATH_VARIABLE,
+						},
+					},
+				},
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_KV_PATH_PREFIX_ATTR,
+			Name:        "discovery_kv_path_prefixes",
+			Description: "Search only secrets with path starting with one of the prefixes, separated by comma. If empty, all secrets are searched",
+			Type:        DATA,
+			Content:     nil,
+			ContentType: STRING,
+			Properties: &DataAttributeProperties{
+				Label:       "Secret path prefixes",
+				Visible:     true,
+				Group:       "KV secrets engines",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
+		DataAttribute{
+			Uuid:        DISCOVERY_KV_MAX_DEPTH_ATTR,
+			Name:        "discovery_kv_max_depth",
+			Description: "Maximum number of nested paths searched below the root of the KV secrets engine",
+			Type:        DATA,
+			Content: []AttributeContent{
+				IntegerAttributeContent{
+					Data: 10,
+				},
+			},
+			ContentType: INTEGER,
+			Properties: &DataAttributeProperties{
+				Label:       "Maximum path depth",
+				Visible:     true,
+				Group:       "KV secrets engines",
+				Required:    false,
+				ReadOnly:    false,
+				List:        false,
+				MultiSelect: false,
+			},
+		},
 	}
 
 }
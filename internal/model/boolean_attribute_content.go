@@ -9,6 +9,14 @@ type BooleanAttributeContent struct {
 	Data bool `json:"data"`
 }
 
+func (a BooleanAttributeContent) GetData() interface{} {
+	return a.Data
+}
+
+func (a BooleanAttributeContent) GetReference() string {
+	return a.Reference
+}
+
 // AssertBooleanAttributeContentRequired checks if the required fields are not zero-ed
 func AssertBooleanAttributeContentRequired(obj BooleanAttributeContent) error {
 	elements := map[string]interface{}{
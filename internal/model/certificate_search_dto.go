@@ -0,0 +1,123 @@
+package model
+
+import "time"
+
+type CertificateSearchRequestDto struct {
+
+	// Authority of the certificates
+	AuthorityUuid string `json:"authorityUuid,omitempty"`
+
+	// Discovery which found the certificates
+	DiscoveryUuid string `json:"discoveryUuid,omitempty"`
+
+	// Secrets engine of the certificates
+	Engine string `json:"engine,omitempty"`
+
+	// Common name of the subject, * matches any characters, case is ignored
+	CommonName string `json:"commonName,omitempty"`
+
+	// Subject alternative name, DNS name, email, IP address or URI
+	San string `json:"san,omitempty"`
+
+	// Distinguished name of the issuer, * matches any characters, case is ignored
+	Issuer string `json:"issuer,omitempty"`
+
+	// Public key algorithm, RSA, ECDSA or Ed25519
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+
+	// Public key size in bits
+	KeySize int `json:"keySize,omitempty"`
+
+	// Signature algorithm, for example SHA256-RSA
+	SignatureAlgorithm string `json:"signatureAlgorithm,omitempty"`
+
+	// SHA-256 fingerprint of the certificate
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Certificates expiring within the number of days, expired certificates are excluded
+	ExpiringWithinDays int `json:"expiringWithinDays,omitempty"`
+
+	// Certificates expiring at or after the time
+	NotAfterFrom *time.Time `json:"notAfterFrom,omitempty"`
+
+	// Certificates expiring at or before the time
+	NotAfterTo *time.Time `json:"notAfterTo,omitempty"`
+
+	// Page number for the retrieved certificates
+	PageNumber int64 `json:"pageNumber"`
+
+	// Number of certificates per page
+	ItemsPerPage int64 `json:"itemsPerPage"`
+
+	// Field to sort the certificates by, one of serialNumber, notAfter, subject, notBefore or commonName
+	SortBy string `json:"sortBy,omitempty"`
+
+	// Sort the certificates in descending order
+	SortDescending bool `json:"sortDescending,omitempty"`
+
+	// Cursor of the next page returned with the previous page, used instead of the page number
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type CertificateSearchResponseDto struct {
+
+	// Number of certificates matching the search
+	TotalItems int64 `json:"totalItems"`
+
+	// Number of pages
+	TotalPages int64 `json:"totalPages"`
+
+	// Cursor of the next page, not set for the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+
+	// Matching certificates
+	Certificates []CertificateSearchResultDto `json:"certificates"`
+}
+
+type CertificateSearchResultDto struct {
+
+	// Certificate identifier
+	Uuid string `json:"uuid"`
+
+	// Serial number of the certificate
+	SerialNumber string `json:"serialNumber"`
+
+	// Authority of the certificate
+	AuthorityUuid string `json:"authorityUuid,omitempty"`
+
+	// Secrets engine of the certificate
+	Engine string `json:"engine,omitempty"`
+
+	// Common name of the subject
+	CommonName string `json:"commonName,omitempty"`
+
+	// Distinguished name of the subject
+	Subject string `json:"subject,omitempty"`
+
+	// Subject alternative names
+	Sans []string `json:"sans,omitempty"`
+
+	// Distinguished name of the issuer
+	Issuer string `json:"issuer,omitempty"`
+
+	// Start of the validity
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+
+	// End of the validity
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+
+	// Public key algorithm
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+
+	// Public key size in bits
+	KeySize int `json:"keySize,omitempty"`
+
+	// Signature algorithm
+	SignatureAlgorithm string `json:"signatureAlgorithm,omitempty"`
+
+	// SHA-256 fingerprint of the certificate
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Base64 encoded PEM of the certificate
+	Base64Content string `json:"base64Content"`
+}
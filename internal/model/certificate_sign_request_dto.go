@@ -17,6 +17,7 @@ type CertificateSignRequestDto struct {
 func (a *CertificateSignRequestDto) Unmarshal(json []byte) {
 	a.Pkcs10 = gjson.GetBytes(json, "pkcs10").String()
 	a.RaProfileAttributes = UnmarshalAttributesValues([]byte(gjson.GetBytes(json, "raProfileAttributes").Raw))
+	a.Attributes = UnmarshalAttributesValues([]byte(gjson.GetBytes(json, "attributes").Raw))
 }
 
 // AssertCertificateSignRequestDtoRequired checks if the required fields are not zero-ed
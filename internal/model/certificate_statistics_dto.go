@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// Expiration buckets of the certificates, the buckets do not overlap
+const (
+	EXPIRATION_EXPIRED  = "expired"
+	EXPIRATION_7_DAYS   = "7d"
+	EXPIRATION_30_DAYS  = "30d"
+	EXPIRATION_90_DAYS  = "90d"
+	EXPIRATION_365_DAYS = "365d"
+	EXPIRATION_LATER    = "later"
+)
+
+type AuthorityStatisticsDto struct {
+
+	// Authority identifier
+	AuthorityUuid string `json:"authorityUuid"`
+
+	// Time when the statistics were calculated, the expiration is relative to it
+	CalculatedAt time.Time `json:"calculatedAt"`
+
+	// Number of Certificates of all engines
+	TotalCertificates int64 `json:"totalCertificates"`
+
+	// Number of revoked Certificates of all engines
+	RevokedCertificates int64 `json:"revokedCertificates"`
+
+	// Statistics of the engines, each based on its latest completed Discovery
+	Engines []EngineStatisticsDto `json:"engines"`
+}
+
+type EngineStatisticsDto struct {
+
+	// Name of the secrets engine
+	Engine string `json:"engine"`
+
+	// Kind of the secrets engine, pki or kv
+	Kind string `json:"kind"`
+
+	// Latest completed Discovery of the engine
+	DiscoveryUuid string `json:"discoveryUuid"`
+
+	// Time when the Discovery was started
+	DiscoveredAt time.Time `json:"discoveredAt"`
+
+	// Number of Certificates
+	TotalCertificates int64 `json:"totalCertificates"`
+
+	// Number of revoked Certificates
+	RevokedCertificates int64 `json:"revokedCertificates"`
+
+	// Number of Certificates by the time to their expiration
+	Expiration []ExpirationBucketDto `json:"expiration"`
+
+	// Number of Certificates by the key algorithm and size
+	KeyAlgorithms []KeyAlgorithmCountDto `json:"keyAlgorithms"`
+}
+
+type ExpirationBucketDto struct {
+
+	// Certificates expired, or expiring within 7, 30, 90 or 365 days and not in the preceding bucket, or later
+	Bucket string `json:"bucket"`
+
+	// Number of Certificates
+	Count int64 `json:"count"`
+}
+
+type KeyAlgorithmCountDto struct {
+
+	// Public key algorithm, empty when the certificate could not be parsed
+	KeyAlgorithm string `json:"keyAlgorithm"`
+
+	// Public key size in bits
+	KeySize int `json:"keySize"`
+
+	// Number of Certificates
+	Count int64 `json:"count"`
+}
@@ -13,6 +13,14 @@ type DateTimeAttributeContent struct {
 	Data time.Time `json:"data"`
 }
 
+func (a DateTimeAttributeContent) GetData() interface{} {
+	return a.Data
+}
+
+func (a DateTimeAttributeContent) GetReference() string {
+	return a.Reference
+}
+
 // AssertDateTimeAttributeContentRequired checks if the required fields are not zero-ed
 func AssertDateTimeAttributeContentRequired(obj DateTimeAttributeContent) error {
 	elements := map[string]interface{}{
@@ -13,6 +13,15 @@ type DiscoveryDataRequestDto struct {
 
 	// Number of certificates per page
 	ItemsPerPage int64 `json:"itemsPerPage"`
+
+	// Field to sort the certificates by, one of serialNumber, notAfter, subject, notBefore or commonName
+	SortBy string `json:"sortBy,omitempty"`
+
+	// Sort the certificates in descending order
+	SortDescending bool `json:"sortDescending,omitempty"`
+
+	// Cursor of the next page returned with the previous page, used instead of the page number
+	Cursor string `json:"cursor,omitempty"`
 }
 
 // AssertDiscoveryDataRequestDtoRequired checks if the required fields are not zero-ed
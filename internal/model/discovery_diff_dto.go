@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+type DiscoveryDiffRequestDto struct {
+
+	// Discovery compared with, the previous completed discovery of the same authority when not specified
+	BaseUuid string `json:"baseUuid,omitempty"`
+
+	// Changes to list, any of added, removed and changed, all when not specified
+	Changes []string `json:"changes,omitempty"`
+
+	// Page number for the retrieved certificates
+	PageNumber int64 `json:"pageNumber"`
+
+	// Number of certificates per page
+	ItemsPerPage int64 `json:"itemsPerPage"`
+
+	// Return all certificates as JSON document to download instead of the page
+	Export bool `json:"export,omitempty"`
+}
+
+type DiscoveryDiffDto struct {
+
+	// Discovery compared with
+	BaseUuid string `json:"baseUuid"`
+
+	// Discovery compared
+	Uuid string `json:"uuid"`
+
+	// Number of certificates found only by the compared discovery
+	Added int64 `json:"added"`
+
+	// Number of certificates found only by the discovery compared with
+	Removed int64 `json:"removed"`
+
+	// Number of certificates found by both discoveries with different revocation
+	Changed int64 `json:"changed"`
+
+	// Page number of the retrieved certificates, not set for the export
+	PageNumber int64 `json:"pageNumber,omitempty"`
+
+	// Number of certificates per page, not set for the export
+	ItemsPerPage int64 `json:"itemsPerPage,omitempty"`
+
+	// Total number of pages of the listed changes, not set for the export
+	TotalPages int64 `json:"totalPages,omitempty"`
+
+	// Changed certificates
+	Certificates []DiscoveryDiffCertificateDto `json:"certificates"`
+}
+
+type DiscoveryDiffCertificateDto struct {
+
+	// Change of the certificate, one of added, removed and changed
+	Change string `json:"change"`
+
+	// Certificate identifier
+	Uuid string `json:"uuid"`
+
+	// Serial number of the certificate
+	SerialNumber string `json:"serialNumber"`
+
+	// Subject of the certificate
+	Subject string `json:"subject,omitempty"`
+
+	// Expiration of the certificate
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+
+	// Secrets engine of the certificate
+	Engine string `json:"engine,omitempty"`
+
+	// Revocation of the certificate found by the discovery compared with
+	RevokedBefore *bool `json:"revokedBefore,omitempty"`
+
+	// Revocation of the certificate found by the compared discovery
+	Revoked *bool `json:"revoked,omitempty"`
+}
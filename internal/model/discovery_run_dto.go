@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+type DiscoveryRunDto struct {
+
+	// Discovery identifier
+	Uuid string `json:"uuid"`
+
+	// Discovery Name
+	Name string `json:"name"`
+
+	Status DiscoveryStatus `json:"status"`
+
+	// Time when the discovery was started
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Number of Certificates discovered
+	TotalCertificatesDiscovered int64 `json:"totalCertificatesDiscovered"`
+
+	// Number of Certificates which could not be read
+	CertificatesFailed int64 `json:"certificatesFailed"`
+
+	// Difference of the number of Certificates discovered to the previous run, not set for the first run
+	CertificatesChange *int64 `json:"certificatesChange,omitempty"`
+}
+
+type DiscoveryRunsDto struct {
+
+	// Total number of runs
+	Total int64 `json:"total"`
+
+	// Latest runs, starting with the most recent
+	Runs []DiscoveryRunDto `json:"runs"`
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+type DiscoveryScheduleRequestDto struct {
+
+	// Name of the schedule, the runs are named after it
+	Name string `json:"name"`
+
+	// Cron expression in UTC, five fields or one of the @yearly, @monthly, @weekly, @daily and @hourly macros
+	Cron string `json:"cron"`
+
+	// Discovery Kind
+	Kind string `json:"kind"`
+
+	// Discovery Provider Attributes of the discoveries started by the schedule
+	Attributes []Attribute `json:"attributes,omitempty"`
+
+	// Schedule is enabled, true when not specified
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Attributes as received, stored to start the discoveries
+	RawAttributes json.RawMessage `json:"-"`
+}
+
+func (a *DiscoveryScheduleRequestDto) Unmarshal(json []byte) {
+	a.Name = gjson.GetBytes(json, "name").String()
+	a.Cron = gjson.GetBytes(json, "cron").String()
+	a.Kind = gjson.GetBytes(json, "kind").String()
+	if enabled := gjson.GetBytes(json, "enabled"); enabled.Exists() {
+		value := enabled.Bool()
+		a.Enabled = &value
+	}
+	a.RawAttributes = []byte(gjson.GetBytes(json, "attributes").Raw)
+	a.Attributes = UnmarshalAttributesValues(a.RawAttributes)
+}
+
+// AssertDiscoveryScheduleRequestDtoRequired checks if the required fields are not zero-ed
+func AssertDiscoveryScheduleRequestDtoRequired(obj DiscoveryScheduleRequestDto) error {
+	elements := map[string]interface{}{
+		"name": obj.Name,
+		"cron": obj.Cron,
+		"kind": obj.Kind,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
+	for _, el := range obj.Attributes {
+		if err := AssertRequestAttributeDtoRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertDiscoveryScheduleRequestDtoConstraints checks if the values respects the defined constraints
+func AssertDiscoveryScheduleRequestDtoConstraints(obj DiscoveryScheduleRequestDto) error {
+	return nil
+}
+
+type DiscoveryScheduleDto struct {
+
+	// Object identifier
+	Uuid string `json:"uuid"`
+
+	// Name of the schedule
+	Name string `json:"name"`
+
+	// Cron expression in UTC
+	Cron string `json:"cron"`
+
+	// Discovery Kind
+	Kind string `json:"kind"`
+
+	// Discovery Provider Attributes of the discoveries started by the schedule
+	Attributes json.RawMessage `json:"attributes"`
+
+	// Schedule is enabled
+	Enabled bool `json:"enabled"`
+
+	// Time of the next run, not set when the schedule is disabled
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+
+	// Time of the last run
+	LastRunAt *time.Time `json:"lastRunAt,omitempty"`
+
+	// Last discovery started by the schedule
+	LastRun *DiscoveryRunDto `json:"lastRun,omitempty"`
+
+	// Time when the last successful discovery completed. Discovery is successful when its status is COMPLETED or WARNING
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+
+	// Last discovery started by the schedule which completed successfully, with status COMPLETED or WARNING
+	LastSuccessDiscoveryUuid string `json:"lastSuccessDiscoveryUuid,omitempty"`
+}
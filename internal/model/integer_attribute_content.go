@@ -9,6 +9,14 @@ type IntegerAttributeContent struct {
 	Data int32 `json:"data"`
 }
 
+func (a IntegerAttributeContent) GetData() interface{} {
+	return a.Data
+}
+
+func (a IntegerAttributeContent) GetReference() string {
+	return a.Reference
+}
+
 // AssertIntegerAttributeContentRequired checks if the required fields are not zero-ed
 func AssertIntegerAttributeContentRequired(obj IntegerAttributeContent) error {
 	elements := map[string]interface{}{
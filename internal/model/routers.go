@@ -15,6 +15,14 @@ type Route struct {
 	HandlerFunc http.HandlerFunc
 }
 
+// StreamedBody is the response body written directly to the http response, used for the content which is too large
+// to be held in memory
+type StreamedBody struct {
+	ContentType string
+	FileName    string
+	Write       func(w io.Writer) error
+}
+
 // Routes is a map of defined api endpoints
 type Routes map[string]Route
 
@@ -64,6 +72,16 @@ func EncodeJSONResponse(i interface{}, status *int, w http.ResponseWriter) error
 		_, err = w.Write(data)
 		return err
 	}
+	if body, ok := i.(*StreamedBody); ok {
+		wHeader.Set("Content-Type", body.ContentType)
+		wHeader.Set("Content-Disposition", "attachment; filename=\""+body.FileName+"\"")
+		if status != nil {
+			w.WriteHeader(*status)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		return body.Write(w)
+	}
 	wHeader.Set("Content-Type", "application/json; charset=UTF-8")
 
 	if status != nil {
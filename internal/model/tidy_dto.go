@@ -0,0 +1,119 @@
+package model
+
+type TidyRequestDto struct {
+
+	// Remove expired certificates from the certificate store
+	TidyCertStore bool `json:"tidyCertStore,omitempty"`
+
+	// Remove expired certificates from the revocation list
+	TidyRevokedCerts bool `json:"tidyRevokedCerts,omitempty"`
+
+	// Remove expired issuers
+	TidyExpiredIssuers bool `json:"tidyExpiredIssuers,omitempty"`
+
+	// Time after the expiration before the certificate is removed, for example 72h
+	SafetyBuffer string `json:"safetyBuffer,omitempty"`
+
+	// Time after the expiration before the issuer is removed, for example 8760h
+	IssuerSafetyBuffer string `json:"issuerSafetyBuffer,omitempty"`
+
+	// Pause between removing the certificates, for example 10ms
+	PauseDuration string `json:"pauseDuration,omitempty"`
+}
+
+type TidyStatusDto struct {
+
+	// State of the tidy operation, one of Inactive, Running, Finished, Error, Cancelling or Cancelled
+	State string `json:"state"`
+
+	// Message describing the state
+	Message string `json:"message,omitempty"`
+
+	// Error of the failed tidy operation
+	Error string `json:"error,omitempty"`
+
+	// Time when the tidy operation started
+	TimeStarted string `json:"timeStarted,omitempty"`
+
+	// Time when the tidy operation finished
+	TimeFinished string `json:"timeFinished,omitempty"`
+
+	// Time when the last automatic tidy operation finished
+	LastAutoTidyFinished string `json:"lastAutoTidyFinished,omitempty"`
+
+	TidyCertStore bool `json:"tidyCertStore"`
+
+	TidyRevokedCerts bool `json:"tidyRevokedCerts"`
+
+	TidyExpiredIssuers bool `json:"tidyExpiredIssuers"`
+
+	// Safety buffer in seconds
+	SafetyBuffer int32 `json:"safetyBuffer,omitempty"`
+
+	// Issuer safety buffer in seconds
+	IssuerSafetyBuffer int32 `json:"issuerSafetyBuffer,omitempty"`
+
+	// Number of certificates removed from the certificate store
+	CertStoreDeletedCount int32 `json:"certStoreDeletedCount"`
+
+	// Number of certificates removed from the revocation list
+	RevokedCertDeletedCount int32 `json:"revokedCertDeletedCount"`
+
+	// Number of revoked certificates with missing issuer
+	MissingIssuerCertCount int32 `json:"missingIssuerCertCount"`
+
+	// Number of certificates in the certificate store
+	CurrentCertStoreCount int32 `json:"currentCertStoreCount"`
+
+	// Number of certificates in the revocation list
+	CurrentRevokedCertCount int32 `json:"currentRevokedCertCount"`
+}
+
+type AutoTidyDto struct {
+
+	// Automatic tidy is enabled
+	Enabled bool `json:"enabled"`
+
+	// Interval between the automatic tidy operations in seconds
+	IntervalDuration int32 `json:"intervalDuration"`
+
+	TidyCertStore bool `json:"tidyCertStore"`
+
+	TidyRevokedCerts bool `json:"tidyRevokedCerts"`
+
+	TidyExpiredIssuers bool `json:"tidyExpiredIssuers"`
+
+	// Safety buffer in seconds
+	SafetyBuffer int32 `json:"safetyBuffer"`
+
+	// Issuer safety buffer in seconds
+	IssuerSafetyBuffer int32 `json:"issuerSafetyBuffer"`
+
+	// Pause between removing the certificates
+	PauseDuration string `json:"pauseDuration,omitempty"`
+}
+
+// AutoTidyRequestDto updates the automatic tidy configuration, the options which are not set are kept
+type AutoTidyRequestDto struct {
+
+	// Enable automatic tidy
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Interval between the automatic tidy operations, for example 12h
+	IntervalDuration *string `json:"intervalDuration,omitempty"`
+
+	TidyCertStore *bool `json:"tidyCertStore,omitempty"`
+
+	TidyRevokedCerts *bool `json:"tidyRevokedCerts,omitempty"`
+
+	TidyExpiredIssuers *bool `json:"tidyExpiredIssuers,omitempty"`
+
+	// Time after the expiration before the certificate is removed, for example 72h
+	SafetyBuffer *string `json:"safetyBuffer,omitempty"`
+
+	// Time after the expiration before the issuer is removed, for example 8760h
+	IssuerSafetyBuffer *string `json:"issuerSafetyBuffer,omitempty"`
+
+	// Pause between removing the certificates, for example 10ms
+	PauseDuration *string `json:"pauseDuration,omitempty"`
+}
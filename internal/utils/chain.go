@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxAiaFetches limits the number of issuer certificates downloaded when completing a chain
+const maxAiaFetches = 5
+
+var aiaClient = &http.Client{Timeout: 10 * time.Second}
+
+// ParseCertificatesPem parses all certificates from the concatenated PEM blocks.
+func ParseCertificatesPem(pemData []string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for _, data := range pemData {
+		rest := []byte(data)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate: %v", err)
+			}
+			certificates = append(certificates, certificate)
+		}
+	}
+	return certificates, nil
+}
+
+// ParseCertificatesValue parses the certificates stored as text value, either as PEM blocks or as base64 encoded DER
+// certificates, possibly concatenated. When the value does not hold any certificate, nil is returned without error.
+func ParseCertificatesValue(value string) ([]*x509.Certificate, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "-----BEGIN") {
+		return ParseCertificatesPem([]string{value})
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil || len(der) == 0 || der[0] != 0x30 {
+		return nil, nil
+	}
+	certificates, err := x509.ParseCertificates(der)
+	if err != nil {
+		return nil, nil
+	}
+	return certificates, nil
+}
+
+// OrderCertificateChain returns the chain of the leaf certificate ordered from the leaf to the root,
+// built from the given certificates. Duplicates and certificates that are not part of the chain are dropped.
+// When leaf is nil, the certificate that is not an issuer of any other given certificate is used as the start of the chain.
+func OrderCertificateChain(leaf *x509.Certificate, certificates []*x509.Certificate) []*x509.Certificate {
+	var unique []*x509.Certificate
+	for _, certificate := range certificates {
+		if !containsCertificate(unique, certificate) && (leaf == nil || !leaf.Equal(certificate)) {
+			unique = append(unique, certificate)
+		}
+	}
+	if leaf == nil {
+		leaf = findChainStart(unique)
+		if leaf == nil {
+			return nil
+		}
+	}
+
+	chain := []*x509.Certificate{leaf}
+	current := leaf
+	for !isSelfSigned(current) {
+		issuer := findIssuer(current, unique)
+		if issuer == nil || containsCertificate(chain, issuer) {
+			break
+		}
+		chain = append(chain, issuer)
+		current = issuer
+	}
+	return chain
+}
+
+// CompleteCertificateChain downloads the missing issuer certificates using the CA issuers URLs
+// of the authority information access extension, until the root is reached.
+func CompleteCertificateChain(ctx context.Context, chain []*x509.Certificate) []*x509.Certificate {
+	for i := 0; i < maxAiaFetches && len(chain) > 0; i++ {
+		last := chain[len(chain)-1]
+		if isSelfSigned(last) {
+			break
+		}
+		issuer := fetchIssuerCertificate(ctx, last)
+		if issuer == nil || containsCertificate(chain, issuer) {
+			break
+		}
+		chain = append(chain, issuer)
+	}
+	return chain
+}
+
+// EncodePkcs7Bundle encodes the certificates as a degenerate PKCS#7 SignedData structure without signers (certs-only).
+func EncodePkcs7Bundle(certificates []*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, certificate := range certificates {
+		raw = append(raw, certificate.Raw...)
+	}
+	signedData := struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: []byte{}},
+		ContentInfo:      struct{ ContentType asn1.ObjectIdentifier }{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: []byte{}},
+	}
+	content, err := asn1.Marshal(signedData)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2},
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+}
+
+func fetchIssuerCertificate(ctx context.Context, certificate *x509.Certificate) *x509.Certificate {
+	for _, url := range certificate.IssuingCertificateURL {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			continue
+		}
+		response, err := aiaClient.Do(request)
+		if err != nil {
+			log.Warn("Failed to fetch issuer certificate from " + url + ": " + err.Error())
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		response.Body.Close()
+		if err != nil || response.StatusCode != http.StatusOK {
+			log.Warn(fmt.Sprintf("Failed to fetch issuer certificate from %s, status %d", url, response.StatusCode))
+			continue
+		}
+		if block, _ := pem.Decode(data); block != nil {
+			data = block.Bytes
+		}
+		issuer, err := x509.ParseCertificate(data)
+		if err != nil {
+			log.Warn("Failed to parse issuer certificate from " + url + ": " + err.Error())
+			continue
+		}
+		if certificate.CheckSignatureFrom(issuer) == nil {
+			return issuer
+		}
+	}
+	return nil
+}
+
+func findChainStart(certificates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range certificates {
+		issuesOther := false
+		for _, certificate := range certificates {
+			if certificate != candidate && isIssuedBy(certificate, candidate) {
+				issuesOther = true
+				break
+			}
+		}
+		if !issuesOther {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func findIssuer(certificate *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if !candidate.Equal(certificate) && isIssuedBy(certificate, candidate) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func isIssuedBy(certificate *x509.Certificate, issuer *x509.Certificate) bool {
+	return bytes.Equal(certificate.RawIssuer, issuer.RawSubject) && certificate.CheckSignatureFrom(issuer) == nil
+}
+
+func isSelfSigned(certificate *x509.Certificate) bool {
+	return bytes.Equal(certificate.RawIssuer, certificate.RawSubject) && certificate.CheckSignatureFrom(certificate) == nil
+}
+
+func containsCertificate(certificates []*x509.Certificate, certificate *x509.Certificate) bool {
+	for _, c := range certificates {
+		if c.Equal(certificate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func createCertificate(t *testing.T, name string, serial int64, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate, key
+}
+
+func TestOrderCertificateChain(t *testing.T) {
+	root, rootKey := createCertificate(t, "root", 1, true, nil, nil)
+	intermediate, intermediateKey := createCertificate(t, "intermediate", 2, true, root, rootKey)
+	leaf, _ := createCertificate(t, "leaf", 3, false, intermediate, intermediateKey)
+	other, _ := createCertificate(t, "other", 4, true, nil, nil)
+
+	chain := OrderCertificateChain(leaf, []*x509.Certificate{root, other, intermediate, root, leaf})
+	if len(chain) != 3 || chain[0] != leaf || chain[1] != intermediate || chain[2] != root {
+		t.Fatalf("unexpected chain %v", chain)
+	}
+
+	chain = OrderCertificateChain(nil, []*x509.Certificate{root, intermediate, intermediate})
+	if len(chain) != 2 || chain[0] != intermediate || chain[1] != root {
+		t.Fatalf("unexpected CA chain %v", chain)
+	}
+}
+
+func TestEncodePkcs7Bundle(t *testing.T) {
+	root, rootKey := createCertificate(t, "root", 1, true, nil, nil)
+	intermediate, intermediateKey := createCertificate(t, "intermediate", 2, true, root, rootKey)
+	leaf, _ := createCertificate(t, "leaf", 3, false, intermediate, intermediateKey)
+
+	bundle, err := EncodePkcs7Bundle([]*x509.Certificate{leaf, intermediate, root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	if rest, err := asn1.Unmarshal(bundle, &contentInfo); err != nil || len(rest) > 0 {
+		t.Fatalf("failed to parse content info: %v", err)
+	}
+	if !contentInfo.ContentType.Equal(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}) {
+		t.Fatalf("unexpected content type %v", contentInfo.ContentType)
+	}
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue `asn1:"tag:0"`
+		SignerInfos      asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		t.Fatalf("failed to parse signed data: %v", err)
+	}
+	certificates, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificates: %v", err)
+	}
+	expected := []*x509.Certificate{leaf, intermediate, root}
+	if len(certificates) != len(expected) {
+		t.Fatalf("expected %d certificates, got %d", len(expected), len(certificates))
+	}
+	for i := range expected {
+		if !certificates[i].Equal(expected[i]) {
+			t.Fatalf("unexpected certificate %d: %s", i, certificates[i].Subject)
+		}
+	}
+}
+
+func TestParseCertificatesValue(t *testing.T) {
+	root, rootKey := createCertificate(t, "root", 1, true, nil, nil)
+	leaf, _ := createCertificate(t, "leaf", 2, false, root, rootKey)
+	chainPem := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw}))
+
+	certificates, err := ParseCertificatesValue(chainPem)
+	if err != nil || len(certificates) != 2 || !certificates[0].Equal(leaf) {
+		t.Fatalf("unexpected PEM certificates %v: %v", certificates, err)
+	}
+
+	certificates, err = ParseCertificatesValue(base64.StdEncoding.EncodeToString(append(leaf.Raw, root.Raw...)))
+	if err != nil || len(certificates) != 2 || !certificates[1].Equal(root) {
+		t.Fatalf("unexpected DER certificates %v: %v", certificates, err)
+	}
+
+	for _, value := range []string{"", "password", base64.StdEncoding.EncodeToString([]byte("0 not a certificate"))} {
+		certificates, err = ParseCertificatesValue(value)
+		if err != nil || certificates != nil {
+			t.Fatalf("unexpected certificates in %q: %v, %v", value, certificates, err)
+		}
+	}
+}
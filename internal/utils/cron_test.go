@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	after := time.Date(2026, time.October, 18, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.October, 18, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.October, 18, 10, 30, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2026, time.October, 19, 2, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, time.October, 18, 11, 0, 0, 0, time.UTC)},
+		{"30 1 * * MON-FRI", time.Date(2026, time.October, 19, 1, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, time.October, 25, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// either day of month or day of week matches when both are restricted
+		{"0 0 20 * 1", time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2026, time.November, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		cron, err := ParseCron(test.expression)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expression, err)
+		}
+		if next := cron.Next(after); !next.Equal(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.expression, test.expected, next)
+		}
+	}
+}
+
+func TestCronNeverMatches(t *testing.T) {
+	cron, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := cron.Next(time.Now()); !next.IsZero() {
+		t.Errorf("expected no match, got %v", next)
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * FOO"} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("%q: expected error", expression)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// CsrPolicy defines the cryptographic requirements on the CSR checked by the connector before it is sent to Vault
+type CsrPolicy struct {
+	VerifySignature          bool
+	MinRsaKeySize            int
+	AllowedEcCurves          []string
+	AllowEd25519             bool
+	ForbiddenSignatureHashes []string
+	FipsOnly                 bool
+}
+
+var fipsEcCurves = []string{"P-256", "P-384", "P-521"}
+
+var fipsSignatureHashes = []string{"SHA256", "SHA384", "SHA512"}
+
+// CheckCsrPolicy verifies the proof of possession of the CSR and checks its key and signature algorithm
+// against the connector policy. All violations found are returned.
+func CheckCsrPolicy(csr *x509.CertificateRequest, policy CsrPolicy) []string {
+	var violations []string
+
+	if policy.VerifySignature {
+		if err := csr.CheckSignature(); err != nil {
+			violations = append(violations, "CSR signature is not valid: "+err.Error())
+		}
+	}
+
+	minRsaKeySize := policy.MinRsaKeySize
+	allowedEcCurves := policy.AllowedEcCurves
+	allowEd25519 := policy.AllowEd25519
+	if policy.FipsOnly {
+		minRsaKeySize = max(minRsaKeySize, 2048)
+		allowedEcCurves = intersect(allowedEcCurves, fipsEcCurves)
+		allowEd25519 = false
+	}
+
+	switch publicKey := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() < minRsaKeySize {
+			violations = append(violations, fmt.Sprintf("RSA key size %d is lower than minimum %d", publicKey.N.BitLen(), minRsaKeySize))
+		}
+	case *ecdsa.PublicKey:
+		curve := publicKey.Curve.Params().Name
+		if !containsFold(allowedEcCurves, curve) {
+			violations = append(violations, fmt.Sprintf("EC curve %s is not allowed, allowed curves are: %s", curve, strings.Join(allowedEcCurves, ", ")))
+		}
+	case ed25519.PublicKey:
+		if !allowEd25519 {
+			violations = append(violations, "Ed25519 keys are not allowed")
+		}
+	default:
+		violations = append(violations, fmt.Sprintf("Public key algorithm %s is not allowed", csr.PublicKeyAlgorithm))
+	}
+
+	hash := signatureHash(csr.SignatureAlgorithm)
+	if containsFold(policy.ForbiddenSignatureHashes, hash) {
+		violations = append(violations, fmt.Sprintf("Signature algorithm %s uses forbidden hash %s", csr.SignatureAlgorithm, hash))
+	} else if policy.FipsOnly && hash != "" && !containsFold(fipsSignatureHashes, hash) {
+		violations = append(violations, fmt.Sprintf("Signature algorithm %s is not FIPS approved", csr.SignatureAlgorithm))
+	}
+
+	return violations
+}
+
+// signatureHash returns the name of the hash function used by the signature algorithm,
+// empty string for algorithms without separate hash function such as Ed25519.
+func signatureHash(algorithm x509.SignatureAlgorithm) string {
+	switch algorithm {
+	case x509.MD2WithRSA:
+		return "MD2"
+	case x509.MD5WithRSA:
+		return "MD5"
+	case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return "SHA1"
+	case x509.SHA256WithRSA, x509.SHA256WithRSAPSS, x509.DSAWithSHA256, x509.ECDSAWithSHA256:
+		return "SHA256"
+	case x509.SHA384WithRSA, x509.SHA384WithRSAPSS, x509.ECDSAWithSHA384:
+		return "SHA384"
+	case x509.SHA512WithRSA, x509.SHA512WithRSAPSS, x509.ECDSAWithSHA512:
+		return "SHA512"
+	case x509.PureEd25519:
+		return ""
+	}
+	return "UNKNOWN"
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.ReplaceAll(v, "-", ""), strings.ReplaceAll(value, "-", "")) {
+			return true
+		}
+	}
+	return false
+}
+
+func intersect(values []string, allowed []string) []string {
+	var result []string
+	for _, v := range values {
+		if containsFold(allowed, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
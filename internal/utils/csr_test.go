@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestCheckCsrPolicy(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:            pkix.Name{CommonName: "test"},
+		SignatureAlgorithm: x509.ECDSAWithSHA256,
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := CsrPolicy{
+		VerifySignature:          true,
+		MinRsaKeySize:            2048,
+		AllowedEcCurves:          []string{"P-224", "P-256"},
+		ForbiddenSignatureHashes: []string{"SHA1", "MD5"},
+	}
+	if violations := CheckCsrPolicy(csr, policy); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+
+	policy.FipsOnly = true
+	if violations := CheckCsrPolicy(csr, policy); len(violations) != 1 {
+		t.Errorf("expected P-224 to be rejected in FIPS mode, got %v", violations)
+	}
+
+	csr.Signature[len(csr.Signature)-1] ^= 0xff
+	policy.FipsOnly = false
+	if violations := CheckCsrPolicy(csr, policy); len(violations) != 1 {
+		t.Errorf("expected invalid signature violation, got %v", violations)
+	}
+}
@@ -2,12 +2,17 @@ package utils
 
 import (
 	"CZERTAINLY-HashiCorp-Vault-Connector/internal/logger"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/md5"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -71,6 +76,19 @@ func ExtractSerialNumber(certificate []byte) (string, error) {
 	return strings.ToLower(strings.Join(hexStr, ":")), nil
 }
 
+// PublicKeyInfo returns the algorithm and size in bits of the public key of the certificate.
+func PublicKeyInfo(certificate *x509.Certificate) (string, int) {
+	switch publicKey := certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", publicKey.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", publicKey.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return certificate.PublicKeyAlgorithm.String(), 0
+}
+
 func GetCertificatesFromDer(pemData []byte) ([]string, error) {
 
 	var certs []string
@@ -99,3 +117,24 @@ func GetCertificatesFromDer(pemData []byte) ([]string, error) {
 
 	return certs, nil
 }
+
+// ParseTtl parses the TTL in the format accepted by Vault, that is number of seconds
+// or duration with s, m, h or d unit suffix.
+func ParseTtl(ttl string) (time.Duration, error) {
+	ttl = strings.TrimSpace(ttl)
+	if ttl == "" {
+		return 0, nil
+	}
+	var duration time.Duration
+	if seconds, err := strconv.ParseInt(ttl, 10, 64); err == nil {
+		duration = time.Duration(seconds) * time.Second
+	} else if days, err := strconv.ParseInt(strings.TrimSuffix(ttl, "d"), 10, 64); err == nil && strings.HasSuffix(ttl, "d") {
+		duration = time.Duration(days) * 24 * time.Hour
+	} else if duration, err = time.ParseDuration(ttl); err != nil {
+		return 0, fmt.Errorf("invalid TTL '%s'", ttl)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("TTL '%s' must not be negative", ttl)
+	}
+	return duration, nil
+}
@@ -0,0 +1,47 @@
+package vault
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+)
+
+// MissingCapabilities checks the capabilities of the client token on the given paths and returns,
+// for every path, the required capabilities that are not granted. Paths with all capabilities granted are omitted.
+func MissingCapabilities(ctx context.Context, client *vault.Client, required map[string][]string) (map[string][]string, error) {
+	paths := make([]string, 0, len(required))
+	for path := range required {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	resp, err := client.System.QueryTokenSelfCapabilities(ctx, schema.QueryTokenSelfCapabilitiesRequest{
+		Paths: paths,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make(map[string][]string)
+	for _, path := range paths {
+		granted := make(map[string]bool)
+		if values, ok := resp.Data[path].([]interface{}); ok {
+			for _, value := range values {
+				if capability, ok := value.(string); ok {
+					granted[capability] = true
+				}
+			}
+		}
+		if granted["root"] {
+			continue
+		}
+		for _, capability := range required[path] {
+			if !granted[capability] {
+				missing[path] = append(missing[path], capability)
+			}
+		}
+	}
+	return missing, nil
+}
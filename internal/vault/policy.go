@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault-client-go/schema"
+	"github.com/ryanuber/go-glob"
+)
+
+// CheckCsrAgainstRole checks the CSR and the requested TTL against the policy of the Vault PKI role
+// the same way Vault does when signing, and returns all violations found. The ttl is ignored when zero.
+func CheckCsrAgainstRole(csr *x509.CertificateRequest, role *schema.PkiReadRoleResponse, ttl time.Duration) []string {
+	var violations []string
+
+	commonName := csr.Subject.CommonName
+	if commonName == "" && role.RequireCn {
+		violations = append(violations, "Common name is required by the role")
+	}
+	if commonName != "" && !isNameAllowed(commonName, role) {
+		violations = append(violations, fmt.Sprintf("Common name '%s' is not allowed by the role", commonName))
+	}
+	for _, dnsName := range csr.DNSNames {
+		if !isNameAllowed(dnsName, role) {
+			violations = append(violations, fmt.Sprintf("DNS name '%s' is not allowed by the role", dnsName))
+		}
+	}
+
+	for _, uri := range csr.URIs {
+		allowed := false
+		for _, pattern := range role.AllowedUriSans {
+			if glob.Glob(pattern, uri.String()) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, fmt.Sprintf("URI SAN '%s' is not allowed by the role", uri.String()))
+		}
+	}
+
+	violations = append(violations, checkKeyAgainstRole(csr, role)...)
+
+	if ttl > 0 && role.MaxTtl > 0 && ttl > time.Duration(role.MaxTtl)*time.Second {
+		violations = append(violations, fmt.Sprintf("Requested TTL %s exceeds the maximum TTL %s of the role", ttl, time.Duration(role.MaxTtl)*time.Second))
+	}
+
+	return violations
+}
+
+func checkKeyAgainstRole(csr *x509.CertificateRequest, role *schema.PkiReadRoleResponse) []string {
+	var violations []string
+	switch role.KeyType {
+	case "rsa":
+		publicKey, ok := csr.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return append(violations, fmt.Sprintf("Key type %s is not allowed by the role, expected RSA", csr.PublicKeyAlgorithm))
+		}
+		if role.KeyBits > 0 && publicKey.N.BitLen() < int(role.KeyBits) {
+			violations = append(violations, fmt.Sprintf("RSA key size %d is lower than %d required by the role", publicKey.N.BitLen(), role.KeyBits))
+		}
+	case "ec":
+		publicKey, ok := csr.PublicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return append(violations, fmt.Sprintf("Key type %s is not allowed by the role, expected EC", csr.PublicKeyAlgorithm))
+		}
+		if role.KeyBits > 0 && publicKey.Curve.Params().BitSize != int(role.KeyBits) {
+			violations = append(violations, fmt.Sprintf("EC key size %d does not match %d required by the role", publicKey.Curve.Params().BitSize, role.KeyBits))
+		}
+	case "ed25519":
+		if _, ok := csr.PublicKey.(ed25519.PublicKey); !ok {
+			violations = append(violations, fmt.Sprintf("Key type %s is not allowed by the role, expected Ed25519", csr.PublicKeyAlgorithm))
+		}
+	}
+	return violations
+}
+
+func isNameAllowed(name string, role *schema.PkiReadRoleResponse) bool {
+	if role.AllowAnyName {
+		return true
+	}
+	name = strings.ToLower(name)
+	// email addresses are validated by their domain part
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name = name[i+1:]
+	}
+	if role.AllowLocalhost && (name == "localhost" || name == "localdomain") {
+		return true
+	}
+	for _, domain := range role.AllowedDomains {
+		domain = strings.ToLower(domain)
+		if role.AllowBareDomains && name == domain {
+			return true
+		}
+		if role.AllowSubdomains && strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+		if role.AllowGlobDomains && strings.Contains(domain, "*") && glob.Glob(domain, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-client-go/schema"
+)
+
+func createCsr(t *testing.T, key any, commonName string, dnsNames []string, uris []*url.URL) *x509.CertificateRequest {
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: dnsNames,
+		URIs:     uris,
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return csr
+}
+
+func TestCheckCsrAgainstRoleAllowed(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	uri, _ := url.Parse("spiffe://example.com/service")
+	csr := createCsr(t, key, "example.com", []string{"www.example.com", "api.test.org"}, []*url.URL{uri})
+	role := &schema.PkiReadRoleResponse{
+		AllowedDomains:   []string{"example.com", "*.test.org"},
+		AllowBareDomains: true,
+		AllowSubdomains:  true,
+		AllowGlobDomains: true,
+		AllowedUriSans:   []string{"spiffe://example.com/*"},
+		KeyType:          "ec",
+		KeyBits:          256,
+		MaxTtl:           3600,
+	}
+
+	if violations := CheckCsrAgainstRole(csr, role, time.Hour); len(violations) != 0 {
+		t.Fatalf("expected no violations, got %v", violations)
+	}
+}
+
+func TestCheckCsrAgainstRoleViolations(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 1024)
+	uri, _ := url.Parse("https://other.org")
+	csr := createCsr(t, key, "example.com", []string{"www.other.org"}, []*url.URL{uri})
+	role := &schema.PkiReadRoleResponse{
+		AllowedDomains:  []string{"example.com"},
+		AllowSubdomains: true,
+		KeyType:         "rsa",
+		KeyBits:         2048,
+		MaxTtl:          3600,
+	}
+
+	violations := CheckCsrAgainstRole(csr, role, 2*time.Hour)
+	// bare domain, DNS name, URI SAN, key size and TTL
+	if len(violations) != 5 {
+		t.Fatalf("expected 5 violations, got %d: %v", len(violations), violations)
+	}
+}
+
+func TestCheckCsrAgainstRoleMaxTtl(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	csr := createCsr(t, key, "www.example.com", nil, nil)
+	role := &schema.PkiReadRoleResponse{
+		AllowedDomains:  []string{"example.com"},
+		AllowSubdomains: true,
+		KeyType:         "any",
+		MaxTtl:          3600,
+	}
+
+	if violations := CheckCsrAgainstRole(csr, role, time.Hour); len(violations) != 0 {
+		t.Fatalf("expected no violations, got %v", violations)
+	}
+	violations := CheckCsrAgainstRole(csr, role, time.Hour+time.Second)
+	if len(violations) != 1 || !strings.Contains(violations[0], "maximum TTL") {
+		t.Fatalf("expected maximum TTL violation, got %v", violations)
+	}
+}
@@ -0,0 +1,15 @@
+create table issuance_records
+(
+    id             serial,
+    fingerprint    varchar   not null unique,
+    authority_uuid varchar   not null,
+    status         varchar   not null,
+    serial_number  varchar   null default null,
+    response       text      null default null,
+    created_at     timestamp not null,
+    updated_at     timestamp not null,
+    expires_at     timestamp not null,
+    primary key (id)
+);
+
+CREATE INDEX index_issuance_records_expires_at ON issuance_records (expires_at);
@@ -0,0 +1,8 @@
+alter table discoveries
+    drop column engines_total,
+    drop column engines_done,
+    drop column certificates_listed,
+    drop column certificates_read,
+    drop column certificates_failed,
+    drop column started_at,
+    drop column estimated_completion_at;
@@ -0,0 +1,8 @@
+alter table discoveries
+    add column engines_total           integer   not null default 0,
+    add column engines_done            integer   not null default 0,
+    add column certificates_listed     integer   not null default 0,
+    add column certificates_read       integer   not null default 0,
+    add column certificates_failed     integer   not null default 0,
+    add column started_at              timestamp null default null,
+    add column estimated_completion_at timestamp null default null;
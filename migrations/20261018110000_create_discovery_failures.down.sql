@@ -0,0 +1 @@
+drop table discovery_failures;
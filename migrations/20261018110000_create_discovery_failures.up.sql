@@ -0,0 +1,13 @@
+create table discovery_failures
+(
+    id            serial,
+    discovery_id  bigint    not null,
+    engine        varchar   not null,
+    serial_number varchar   null default null,
+    reason        text      not null,
+    created_at    timestamp not null,
+    primary key (id),
+    foreign key (discovery_id) references discoveries (id) on delete cascade
+);
+
+CREATE INDEX index_discovery_failures_discovery_id ON discovery_failures (discovery_id);
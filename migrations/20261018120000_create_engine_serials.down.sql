@@ -0,0 +1 @@
+drop table engine_serials;
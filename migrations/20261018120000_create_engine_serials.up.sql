@@ -0,0 +1,11 @@
+create table engine_serials
+(
+    authority_uuid varchar   not null,
+    engine         varchar   not null,
+    serial_number  varchar   not null,
+    certificate_id bigint    not null,
+    revoked        boolean   not null default false,
+    created_at     timestamp not null,
+    primary key (authority_uuid, engine, serial_number),
+    foreign key (certificate_id) references certificates (id) on delete cascade
+);
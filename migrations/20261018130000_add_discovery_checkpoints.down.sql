@@ -0,0 +1,5 @@
+drop table discovery_engines;
+
+alter table discoveries
+    drop column authority_uuid,
+    drop column heartbeat_at;
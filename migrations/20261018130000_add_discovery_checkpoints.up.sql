@@ -0,0 +1,16 @@
+alter table discoveries
+    add column authority_uuid varchar   null default null,
+    add column heartbeat_at   timestamp null default null;
+
+create table discovery_engines
+(
+    id           serial,
+    discovery_id bigint  not null,
+    engine       varchar not null,
+    kind         varchar not null,
+    done         boolean not null default false,
+    primary key (id),
+    foreign key (discovery_id) references discoveries (id) on delete cascade
+);
+
+CREATE INDEX index_discovery_engines_discovery_id ON discovery_engines (discovery_id);
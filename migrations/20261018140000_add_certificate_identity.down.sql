@@ -0,0 +1,10 @@
+DROP INDEX index_certificates_fingerprint;
+DROP INDEX index_certificates_authority_engine_serial_number;
+
+CREATE INDEX index_certificates_uuid ON certificates (uuid);
+
+alter table certificates
+    drop column authority_uuid,
+    drop column engine,
+    drop column issuer_id,
+    drop column fingerprint;
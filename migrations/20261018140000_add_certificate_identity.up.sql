@@ -0,0 +1,11 @@
+alter table certificates
+    add column authority_uuid varchar null default null,
+    add column engine         varchar null default null,
+    add column issuer_id      varchar null default null,
+    add column fingerprint    varchar null default null;
+
+-- uuid is unique already, the identity of the certificate is derived into it
+DROP INDEX index_certificates_uuid;
+
+CREATE INDEX index_certificates_authority_engine_serial_number ON certificates (authority_uuid, engine, serial_number);
+CREATE INDEX index_certificates_fingerprint ON certificates (fingerprint);
@@ -0,0 +1,6 @@
+DROP INDEX index_certificates_subject;
+DROP INDEX index_certificates_not_after;
+
+alter table certificates
+    drop column not_after,
+    drop column subject;
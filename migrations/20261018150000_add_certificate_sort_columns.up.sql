@@ -0,0 +1,12 @@
+alter table certificates
+    add column not_after timestamp null default null,
+    add column subject   varchar   null default null;
+
+update certificates
+set not_after = (meta::jsonb ->> 'notAfter')::timestamptz at time zone 'UTC',
+    subject   = meta::jsonb ->> 'subject'
+where meta is not null
+  and meta <> '';
+
+CREATE INDEX index_certificates_not_after ON certificates (not_after);
+CREATE INDEX index_certificates_subject ON certificates (subject);
@@ -0,0 +1,12 @@
+DROP INDEX index_discoveries_authority_uuid_created_at;
+
+alter table discoveries
+    drop column created_at;
+
+DROP INDEX index_discovery_certificates_discovery_id;
+
+alter table discovery_certificates
+    drop constraint discovery_certificates_certificate_id_fkey,
+    drop constraint discovery_certificates_discovery_id_fkey,
+    add constraint discovery_certificates_certificate_id_fkey foreign key (certificate_id) references certificates (id),
+    add constraint discovery_certificates_discovery_id_fkey foreign key (discovery_id) references discoveries (id);
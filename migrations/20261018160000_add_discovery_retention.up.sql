@@ -0,0 +1,16 @@
+alter table discovery_certificates
+    drop constraint discovery_certificates_certificate_id_fkey,
+    drop constraint discovery_certificates_discovery_id_fkey,
+    add constraint discovery_certificates_certificate_id_fkey foreign key (certificate_id) references certificates (id) on delete cascade,
+    add constraint discovery_certificates_discovery_id_fkey foreign key (discovery_id) references discoveries (id) on delete cascade;
+
+CREATE INDEX index_discovery_certificates_discovery_id ON discovery_certificates (discovery_id);
+
+alter table discoveries
+    add column created_at timestamp not null default now();
+
+update discoveries
+set created_at = started_at
+where started_at is not null;
+
+CREATE INDEX index_discoveries_authority_uuid_created_at ON discoveries (authority_uuid, created_at);
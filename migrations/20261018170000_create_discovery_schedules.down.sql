@@ -0,0 +1,6 @@
+DROP INDEX index_discoveries_schedule_id;
+
+alter table discoveries
+    drop column schedule_id;
+
+drop table discovery_schedules;
@@ -0,0 +1,24 @@
+create table discovery_schedules
+(
+    id                     serial,
+    uuid                   varchar   not null unique,
+    name                   varchar   not null unique,
+    cron                   varchar   not null,
+    kind                   varchar   not null,
+    attributes             text      not null,
+    enabled                boolean   not null default true,
+    next_run_at            timestamp null default null,
+    last_run_at            timestamp null default null,
+    last_success_at        timestamp null default null,
+    last_success_discovery varchar   null default null,
+    created_at             timestamp not null,
+    updated_at             timestamp not null,
+    primary key (id)
+);
+
+CREATE INDEX index_discovery_schedules_next_run_at ON discovery_schedules (next_run_at);
+
+alter table discoveries
+    add column schedule_id bigint null default null references discovery_schedules (id) on delete set null;
+
+CREATE INDEX index_discoveries_schedule_id ON discoveries (schedule_id);
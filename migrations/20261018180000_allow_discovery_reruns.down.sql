@@ -0,0 +1,12 @@
+DROP INDEX index_discoveries_name_created_at;
+
+-- only the latest run of each discovery is kept
+delete
+from discoveries
+where id in (select id
+             from (select id, row_number() over (partition by name order by created_at desc, id desc) as position
+                   from discoveries) ranked
+             where position > 1);
+
+alter table discoveries
+    add constraint discoveries_name_key unique (name);
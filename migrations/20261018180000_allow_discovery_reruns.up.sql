@@ -0,0 +1,5 @@
+-- runs of the same discovery share the name, each of them has its own uuid
+alter table discoveries
+    drop constraint discoveries_name_key;
+
+CREATE INDEX index_discoveries_name_created_at ON discoveries (name, created_at);
@@ -0,0 +1,2 @@
+alter table discovery_certificates
+    drop column revoked;
@@ -0,0 +1,10 @@
+-- revocation of the certificate when it was discovered, so that the runs of the discovery can be compared
+alter table discovery_certificates
+    add column revoked boolean not null default false;
+
+update discovery_certificates dc
+set revoked = coalesce((c.meta::jsonb ->> 'revoked')::boolean, false)
+from certificates c
+where c.id = dc.certificate_id
+  and c.meta is not null
+  and c.meta <> '';
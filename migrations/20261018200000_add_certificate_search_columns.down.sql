@@ -0,0 +1,15 @@
+DROP INDEX index_certificates_engine_not_after;
+DROP INDEX index_certificates_key_algorithm_size;
+DROP INDEX index_certificates_not_before;
+DROP INDEX index_certificates_issuer_dn;
+DROP INDEX index_certificates_sans;
+DROP INDEX index_certificates_common_name;
+
+alter table certificates
+    drop column common_name,
+    drop column sans,
+    drop column issuer_dn,
+    drop column not_before,
+    drop column key_algorithm,
+    drop column key_size,
+    drop column signature_algorithm;
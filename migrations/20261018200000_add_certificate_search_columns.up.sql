@@ -0,0 +1,21 @@
+alter table certificates
+    add column common_name         varchar   null default null,
+    add column sans                varchar[] null default null,
+    add column issuer_dn           varchar   null default null,
+    add column not_before          timestamp null default null,
+    add column key_algorithm       varchar   null default null,
+    add column key_size            integer   null default null,
+    add column signature_algorithm varchar   null default null;
+
+-- the remaining columns are parsed from the content of the stored certificates by the connector
+update certificates
+set not_before = (meta::jsonb ->> 'notBefore')::timestamptz at time zone 'UTC'
+where meta is not null
+  and meta <> '';
+
+CREATE INDEX index_certificates_common_name ON certificates (lower(common_name) varchar_pattern_ops);
+CREATE INDEX index_certificates_sans ON certificates USING gin (sans);
+CREATE INDEX index_certificates_issuer_dn ON certificates (issuer_dn);
+CREATE INDEX index_certificates_not_before ON certificates (not_before);
+CREATE INDEX index_certificates_key_algorithm_size ON certificates (key_algorithm, key_size, not_after);
+CREATE INDEX index_certificates_engine_not_after ON certificates (engine, not_after);
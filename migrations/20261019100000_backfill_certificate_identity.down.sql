@@ -0,0 +1,3 @@
+DROP INDEX index_certificates_fingerprint_null;
+
+-- the backfilled identity is kept, the columns are dropped by the migration which added them
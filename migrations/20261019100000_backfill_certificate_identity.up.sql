@@ -0,0 +1,22 @@
+-- certificates stored before their identity was added get the engine and issuer from their metadata
+update certificates
+set engine    = meta::jsonb ->> 'engine',
+    issuer_id = coalesce(meta::jsonb ->> 'issuerId', '')
+where engine is null
+  and meta is not null
+  and meta <> ''
+  and coalesce(meta::jsonb ->> 'engine', '') <> '';
+
+-- and the authority of the latest discovery which found them
+update certificates c
+set authority_uuid = latest.authority_uuid
+from (select distinct on (dc.certificate_id) dc.certificate_id, d.authority_uuid
+      from discovery_certificates dc
+               join discoveries d on d.id = dc.discovery_id
+      where d.authority_uuid is not null
+      order by dc.certificate_id, d.created_at desc, d.id desc) latest
+where c.id = latest.certificate_id
+  and c.authority_uuid is null;
+
+-- the fingerprint and uuid are computed by the connector from the content of the certificates
+CREATE INDEX index_certificates_fingerprint_null ON certificates (id) WHERE fingerprint IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX index_certificates_key_algorithm_size;
+DROP INDEX index_certificates_issuer_dn;
+
+CREATE INDEX index_certificates_issuer_dn ON certificates (issuer_dn);
+CREATE INDEX index_certificates_key_algorithm_size ON certificates (key_algorithm, key_size, not_after);
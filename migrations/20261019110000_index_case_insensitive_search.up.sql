@@ -0,0 +1,6 @@
+-- issuer and key algorithm are searched case-insensitive, as the common name
+DROP INDEX index_certificates_issuer_dn;
+DROP INDEX index_certificates_key_algorithm_size;
+
+CREATE INDEX index_certificates_issuer_dn ON certificates (lower(issuer_dn) varchar_pattern_ops);
+CREATE INDEX index_certificates_key_algorithm_size ON certificates (lower(key_algorithm), key_size, not_after);
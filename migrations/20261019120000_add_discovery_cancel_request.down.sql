@@ -0,0 +1,2 @@
+alter table discoveries
+    drop column cancel_requested_at;
@@ -0,0 +1,2 @@
+alter table discoveries
+    add column cancel_requested_at timestamp null default null;